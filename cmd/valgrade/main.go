@@ -1,25 +1,43 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 
+	"github.com/cstanislawski/helm-valgrade/internal/changelog"
 	"github.com/cstanislawski/helm-valgrade/internal/chart"
 	"github.com/cstanislawski/helm-valgrade/internal/config"
+	"github.com/cstanislawski/helm-valgrade/internal/crd"
 	"github.com/cstanislawski/helm-valgrade/internal/diff"
+	"github.com/cstanislawski/helm-valgrade/internal/preflight"
+	"github.com/cstanislawski/helm-valgrade/internal/render"
+	"github.com/cstanislawski/helm-valgrade/internal/report"
+	"github.com/cstanislawski/helm-valgrade/internal/schema"
+	"github.com/cstanislawski/helm-valgrade/internal/templates"
+	"github.com/cstanislawski/helm-valgrade/internal/textdiff"
 	"github.com/cstanislawski/helm-valgrade/internal/values"
 )
 
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitUpgradeAvailable = 2
+	exitConflicts        = 3
+)
+
 func main() {
 	cfg, err := config.Parse()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing configuration: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	if cfg.Help {
@@ -29,73 +47,457 @@ func main() {
 
 	setupLogger(cfg.LogLevel, cfg.Silent)
 
-	errors := run(cfg)
+	code, errors := run(cfg)
 	if len(errors) > 0 {
 		log.Error().Msg("Failed to execute valgrade")
 		for _, err := range errors {
 			log.Error().Msgf("%v", err)
 		}
-		os.Exit(1)
+		os.Exit(exitError)
+	}
+
+	if code != exitConflicts {
+		log.Info().Msg("Valgrade completed successfully")
+	}
+	os.Exit(code)
+}
+
+func run(cfg *config.Config) (int, []error) {
+	switch cfg.Command {
+	case config.CommandMinimize:
+		if errors := runMinimize(cfg); len(errors) > 0 {
+			return exitError, errors
+		}
+		return exitOK, nil
+	case config.CommandExplain:
+		if errors := runExplain(cfg); len(errors) > 0 {
+			return exitError, errors
+		}
+		return exitOK, nil
+	default:
+		return runUpgrade(cfg)
+	}
+}
+
+func runUpgrade(cfg *config.Config) (int, []error) {
+	rep := report.New(cfg.ChartName, cfg.Repository, cfg.VersionBase, cfg.VersionTarget, cfg.ValuesFiles)
+
+	changed, errors := upgrade(cfg, rep)
+	if cfg.ReportFormat != "" {
+		for _, err := range errors {
+			rep.Fail(err)
+		}
+		format, _ := report.ParseFormat(cfg.ReportFormat)
+		if err := rep.Write(cfg.ReportFile, format); err != nil {
+			errors = append(errors, fmt.Errorf("failed to write report: %w", err))
+		}
+	}
+	if len(errors) > 0 {
+		return exitError, errors
 	}
 
-	log.Info().Msg("Valgrade completed successfully")
+	if cfg.Command != config.CommandCheck {
+		return exitOK, nil
+	}
+	switch {
+	case rep.Summary.Conflicts > 0:
+		log.Warn().Int("conflicts", rep.Summary.Conflicts).Msg("Upgrade requires manual conflict resolution")
+		return exitConflicts, nil
+	case changed:
+		log.Info().Msg("Upgrade available")
+		return exitUpgradeAvailable, nil
+	default:
+		log.Info().Msg("Values are up to date")
+		return exitOK, nil
+	}
 }
 
-func run(cfg *config.Config) []error {
+func upgrade(cfg *config.Config, rep *report.Report) (bool, []error) {
 	var errors []error
 
-	baseChart, err := chart.Fetch(cfg.Repository, cfg.ChartName, cfg.VersionBase, nil)
+	baseChart, targetChart, err := fetchCharts(cfg)
 	if err != nil {
-		errors = append(errors, fmt.Errorf("failed to fetch base chart: %w", err))
-		return errors
+		errors = append(errors, err)
+		return false, errors
 	}
+	rep.Resolve(baseChart, targetChart)
 
-	targetChart, err := chart.Fetch(cfg.Repository, cfg.ChartName, cfg.VersionTarget, nil)
+	releases := collectChangelog(cfg.Repository, baseChart, targetChart)
+	reportChangelog(releases)
+	rep.AddChangelog(releases...)
+
+	findings, err := preflight.Run(baseChart, targetChart, preflight.Options{
+		KubeVersion: cfg.KubeVersion,
+		Strict:      cfg.Strict,
+	})
 	if err != nil {
-		errors = append(errors, fmt.Errorf("failed to fetch target chart: %w", err))
-		return errors
+		errors = append(errors, fmt.Errorf("failed to run preflight checks: %w", err))
+		return false, errors
+	}
+	for _, finding := range findings {
+		if finding.Severity == preflight.SeverityWarning {
+			log.Warn().Str("check", finding.Check).Msg(finding.Message)
+			rep.Warn(report.CategoryPreflight, "", finding.Message)
+		}
+	}
+	for _, finding := range preflight.Blocking(findings) {
+		errors = append(errors, fmt.Errorf("preflight check %s failed: %s", finding.Check, finding.Message))
+	}
+	if len(errors) > 0 {
+		return false, errors
 	}
 
-	userValues, err := values.Load(cfg.ValuesFile)
+	user, err := loadValues(cfg)
 	if err != nil {
-		errors = append(errors, fmt.Errorf("failed to load user values: %w", err))
-		return errors
+		errors = append(errors, err)
+		return false, errors
 	}
+	valuesFiles := user.files
 
-	userValuesMap := make(map[string]interface{})
-	if err := userValues.Decode(&userValuesMap); err != nil {
-		errors = append(errors, fmt.Errorf("failed to decode user values: %w", err))
-		return errors
+	userValuesMap, err := user.tree.Decode()
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to merge user values: %w", err))
+		return false, errors
 	}
+	userPaths := user.fileTree.LeafPaths()
+	overridePaths := user.overridesTree.LeafPaths()
 
-	diffResult, err := diff.Compare(baseChart, targetChart, userValuesMap, cfg.KeepValues, cfg.IgnoreMissing)
+	diffResult, err := diff.CompareTree(baseChart, targetChart, user.tree, diffOptions(cfg, user.pins))
 	if err != nil {
 		errors = append(errors, fmt.Errorf("failed to compare charts: %w", err))
-		return errors
+		return false, errors
 	}
+	rep.AddDecisions(diffResult.Decisions)
+	for _, decision := range diffResult.Decisions {
+		if related := changelog.Related(releases, decision.Path); len(related) > 0 {
+			log.Info().Str("path", decision.Path).Strs("changelog", related).Msg("Changelog mentions a changed value")
+		}
+	}
+
+	staleOverrides, err := diff.StaleOverrides(diffResult, overridePaths)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to check overrides: %w", err))
+		return false, errors
+	}
+	reportStaleOverrides(rep, staleOverrides)
+
+	for _, warning := range diffResult.Warnings {
+		log.Warn().Str("path", warning.Path).Msg(warning.Message)
+		rep.Warn(report.CategoryValue, warning.Path, warning.Message)
+	}
+
+	reportDefaultChanges(diffResult.Defaults)
+	reportOrphans(rep, diffResult.Orphans)
+
+	reportSchemaChanges(rep, user.tree, schema.Diff(baseChart, targetChart, userValuesMap))
+
+	scanTemplates(rep, baseChart, targetChart, userPaths)
 
-	upgradedValues, upgradeErrors := applyUpgrades(diffResult, userValues)
+	crdChanges, err := crd.Compare(baseChart, targetChart)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to compare CRDs: %w", err))
+		return false, errors
+	}
+	reportCRDChanges(rep, crdChanges)
+
+	newValuesFile := valuesFiles[0]
+	if cfg.NewValuesFile != "" {
+		newValuesFile = values.Find(valuesFiles, cfg.NewValuesFile)
+	}
+
+	upgradeErrors := applyUpgrades(rep, diffResult, user.tree, valuesFiles, newValuesFile, overridePaths)
 	if len(upgradeErrors) > 0 {
 		for _, err := range upgradeErrors {
 			errors = append(errors, fmt.Errorf("failed to apply upgrades: %w", err))
 		}
-		return errors
+		return false, errors
+	}
+
+	upgradedTree := values.MergeTrees(values.FileTree(valuesFiles), user.overridesTree)
+	upgradedValuesMap, err := upgradedTree.Decode()
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to merge upgraded values: %w", err))
+		return false, errors
 	}
+	changed := !reflect.DeepEqual(userValuesMap, upgradedValuesMap)
 
-	if cfg.DryRun {
-		if err := printUpgradedValues(upgradedValues); err != nil {
+	violations, err := schema.Validate(targetChart, upgradedValuesMap)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to validate upgraded values: %w", err))
+		return false, errors
+	}
+	for _, violation := range violations {
+		err := fmt.Errorf("schema violation at %s: %s", locate(upgradedTree, violation.Path), violation.Message)
+		if cfg.SkipSchemaValidation {
+			log.Warn().Msg(err.Error())
+			rep.Warn(report.CategorySchema, violation.Path.String(), err.Error())
+			continue
+		}
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		return false, errors
+	}
+
+	if cfg.VerifyRender {
+		if renderErrors := verifyRender(rep, cfg, baseChart, targetChart, userValuesMap, upgradedValuesMap); len(renderErrors) > 0 {
+			return false, renderErrors
+		}
+	}
+
+	if cfg.Command == config.CommandCheck {
+		return changed, errors
+	}
+
+	if cfg.DryRun != "" {
+		if err := printDryRun(cfg, valuesFiles); err != nil {
 			errors = append(errors, fmt.Errorf("failed to print upgraded values: %w", err))
 		}
+		return changed, errors
+	}
+
+	if err := writeOutput(valuesFiles, cfg.OutputFile, cfg.InPlace); err != nil {
+		errors = append(errors, fmt.Errorf("failed to write output: %w", err))
+	}
+
+	return changed, errors
+}
+
+func runMinimize(cfg *config.Config) []error {
+	var errors []error
+
+	baseChart, err := chart.Fetch(cfg.Repository, cfg.ChartName, cfg.VersionBase, nil)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to fetch base chart: %w", err))
 		return errors
 	}
 
-	if err := writeOutput(upgradedValues, cfg.OutputFile, cfg.InPlace, cfg.ValuesFile); err != nil {
+	valuesFiles, err := values.LoadFiles(cfg.ValuesFiles, cfg.Document)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to load user values: %w", err))
+		return errors
+	}
+
+	keepPaths, err := values.ParsePaths(cfg.KeepValues)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to parse keep values: %w", err))
+		return errors
+	}
+
+	pruned := 0
+	for i := len(valuesFiles) - 1; i >= 0; i-- {
+		f := valuesFiles[i]
+
+		fallback, err := values.Merge(valuesFiles[:i])
+		if err != nil {
+			errors = append(errors, fmt.Errorf("failed to merge user values: %w", err))
+			return errors
+		}
+		fallback = values.MergeMaps(baseChart.GetDefaultValues(), fallback)
+
+		current, err := values.Merge(valuesFiles[i : i+1])
+		if err != nil {
+			errors = append(errors, fmt.Errorf("failed to decode user values: %w", err))
+			return errors
+		}
+
+		protected := append([]values.Path{}, keepPaths...)
+		if cfg.KeepPinned {
+			protected = append(protected, values.Pinned(f.Node)...)
+		}
+
+		for _, path := range diff.Redundant(fallback, current, diff.Options{CaseInsensitiveBools: cfg.CaseInsensitiveBools}) {
+			if isProtected(path, protected) {
+				log.Debug().Str("file", f.Path).Str("path", path.String()).Msg("Keeping redundant override")
+				continue
+			}
+			if err := values.Prune(f.Node, path); err != nil {
+				errors = append(errors, fmt.Errorf("failed to remove redundant value %s from %s: %w", path, f.Path, err))
+				continue
+			}
+			pruned++
+			log.Info().Str("file", f.Path).Str("path", path.String()).Msg("Removed redundant override")
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	log.Info().Int("count", pruned).Msg("Minimized values")
+
+	if cfg.DryRun != "" {
+		if err := printDryRun(cfg, valuesFiles); err != nil {
+			errors = append(errors, fmt.Errorf("failed to print minimized values: %w", err))
+		}
+		return errors
+	}
+
+	if err := writeOutput(valuesFiles, cfg.OutputFile, cfg.InPlace); err != nil {
 		errors = append(errors, fmt.Errorf("failed to write output: %w", err))
 	}
 
 	return errors
 }
 
+func runExplain(cfg *config.Config) []error {
+	var errors []error
+
+	baseChart, targetChart, err := fetchCharts(cfg)
+	if err != nil {
+		errors = append(errors, err)
+		return errors
+	}
+
+	user, err := loadValues(cfg)
+	if err != nil {
+		errors = append(errors, err)
+		return errors
+	}
+
+	path, err := values.ParsePath(cfg.ExplainPath)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to parse path: %w", err))
+		return errors
+	}
+
+	explanation, err := diff.ExplainTree(baseChart, targetChart, user.tree, path, diffOptions(cfg, user.pins))
+	if err != nil {
+		errors = append(errors, fmt.Errorf("failed to explain %s: %w", path, err))
+		return errors
+	}
+	for _, override := range user.overridesTree.LeafPaths() {
+		if override.HasPrefix(path) || path.HasPrefix(override) {
+			explanation.Rules = append(explanation.Rules, fmt.Sprintf("%s is set on the command line and is never written", override))
+		}
+	}
+
+	printExplanation(explanation)
+	return errors
+}
+
+type userInput struct {
+	files         []*values.File
+	fileTree      *values.Tree
+	overridesTree *values.Tree
+	tree          *values.Tree
+	pins          []values.Path
+}
+
+func fetchCharts(cfg *config.Config) (*chart.Chart, *chart.Chart, error) {
+	baseChart, err := chart.Fetch(cfg.Repository, cfg.ChartName, cfg.VersionBase, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch base chart: %w", err)
+	}
+
+	targetChart, err := chart.Fetch(cfg.Repository, cfg.ChartName, cfg.VersionTarget, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch target chart: %w", err)
+	}
+
+	return baseChart, targetChart, nil
+}
+
+func loadValues(cfg *config.Config) (*userInput, error) {
+	valuesFiles, err := values.LoadFiles(cfg.ValuesFiles, cfg.Document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user values: %w", err)
+	}
+
+	overrides, err := cfg.Overrides.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse overrides: %w", err)
+	}
+	overridesTree, err := values.TreeFromMap(overrides, "--set")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse overrides: %w", err)
+	}
+
+	user := &userInput{
+		files:         valuesFiles,
+		fileTree:      values.FileTree(valuesFiles),
+		overridesTree: overridesTree,
+	}
+	user.tree = values.MergeTrees(user.fileTree, overridesTree)
+	for _, f := range valuesFiles {
+		user.pins = append(user.pins, values.Pinned(f.Node)...)
+	}
+
+	return user, nil
+}
+
+func diffOptions(cfg *config.Config, pins []values.Path) diff.Options {
+	return diff.Options{
+		KeepValues:           cfg.KeepValues,
+		IgnoreMissing:        cfg.IgnoreMissing,
+		PinDefaults:          cfg.PinDefaults,
+		PinUserValues:        cfg.PinUserValues,
+		CaseInsensitiveBools: cfg.CaseInsensitiveBools,
+		EmbeddedPaths:        cfg.EmbeddedPaths,
+		Pins:                 pins,
+	}
+}
+
+func printExplanation(e *diff.Explanation) {
+	user := "not set"
+	if e.UserSet {
+		user = displayValue(e.User)
+		if position := e.Position.String(); position != "" {
+			user = fmt.Sprintf("%s (%s)", user, position)
+		}
+	}
+
+	fmt.Printf("path:           %s\n", e.Path)
+	fmt.Printf("base default:   %s\n", formatDefault(e.Base, e.InBase))
+	fmt.Printf("target default: %s\n", formatDefault(e.Target, e.InTarget))
+	fmt.Printf("user value:     %s\n", user)
+
+	if len(e.Rules) == 0 {
+		fmt.Println("rules:          none")
+	} else {
+		fmt.Println("rules:")
+		for _, rule := range e.Rules {
+			fmt.Printf("  - %s\n", rule)
+		}
+	}
+
+	if len(e.Decisions) == 0 {
+		fmt.Println("decision:       none, the default did not change between the versions")
+		return
+	}
+	fmt.Println("decision:")
+	for _, d := range e.Decisions {
+		fmt.Printf("  - %s: %s (%s), %s\n", d.Path, d.Action, d.Change, d.Reason)
+		if d.Conflict {
+			fmt.Printf("    conflict: the user value %s differs from the new default %s\n", displayValue(d.User), displayValue(d.Target))
+		}
+	}
+}
+
+func formatDefault(v interface{}, exists bool) string {
+	if !exists {
+		return "not set"
+	}
+	return displayValue(v)
+}
+
+func displayValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func isProtected(path values.Path, protected []values.Path) bool {
+	for _, p := range protected {
+		if path.HasPrefix(p) {
+			return true
+		}
+	}
+	return false
+}
+
 func setupLogger(level string, silent bool) {
 	if silent {
 		zerolog.SetGlobalLevel(zerolog.Disabled)
@@ -123,53 +525,358 @@ func getLogLevel(level string) zerolog.Level {
 	}
 }
 
-func applyUpgrades(diffResult *diff.Result, userValues *yaml.Node) (*yaml.Node, []error) {
+func collectChangelog(repository string, base, target *chart.Chart) []changelog.Release {
+	var releases []changelog.Release
+
+	index, err := chart.FetchIndex(repository)
+	if err != nil {
+		log.Debug().Err(err).Msg("Repository index is not available, using the changelog of the target version only")
+		release, err := changelog.FromAnnotations(target.Metadata)
+		if err != nil {
+			log.Warn().Msg(err.Error())
+		} else if release != nil {
+			releases = append(releases, *release)
+		}
+	} else {
+		releases, err = changelog.FromIndex(index, target.GetName(), base.GetVersion(), target.GetVersion())
+		if err != nil {
+			log.Warn().Msg(err.Error())
+		}
+	}
+
+	return changelog.Merge(releases, changelog.FromChart(target.Chart, base.GetVersion(), target.GetVersion()))
+}
+
+func reportChangelog(releases []changelog.Release) {
+	for _, release := range releases {
+		for _, change := range release.Changes {
+			event := log.Debug().Str("version", release.Version)
+			if change.Kind != "" {
+				event = event.Str("kind", change.Kind)
+			}
+			event.Msg(change.Description)
+		}
+	}
+}
+
+func reportStaleOverrides(rep *report.Report, stale []diff.StaleOverride) {
+	for _, override := range stale {
+		if len(override.Candidates) > 0 {
+			candidates := make([]string, 0, len(override.Candidates))
+			for _, candidate := range override.Candidates {
+				candidates = append(candidates, candidate.String())
+			}
+			log.Warn().Str("path", override.Path.String()).Strs("candidates", candidates).Msg("Override no longer applies in the target version and may need to move")
+			rep.Warn(report.CategoryOverride, override.Path.String(), fmt.Sprintf("override no longer applies in the target version and may need to move to %s", strings.Join(candidates, ", ")))
+			continue
+		}
+		log.Warn().Str("path", override.Path.String()).Msg("Override no longer applies in the target version")
+		rep.Warn(report.CategoryOverride, override.Path.String(), "override no longer applies in the target version")
+	}
+}
+
+func locate(tree *values.Tree, path values.Path) string {
+	position, ok := tree.Locate(path)
+	if !ok {
+		return path.String()
+	}
+	return fmt.Sprintf("%s (%s)", path, position)
+}
+
+func reportSchemaChanges(rep *report.Report, tree *values.Tree, changes []schema.Change) {
+	for _, change := range changes {
+		if change.Kind == schema.ChangeInvalid {
+			log.Warn().Str("chart", change.Path.String()).Msg(change.Message)
+			rep.Warn(report.CategorySchema, change.Path.String(), change.Message)
+			continue
+		}
+		log.Warn().Str("kind", string(change.Kind)).Msgf("Schema change at %s: %s", locate(tree, change.Path), change.Message)
+		rep.Warn(report.CategorySchema, change.Path.String(), change.Message)
+	}
+}
+
+func reportDefaultChanges(defaults *diff.Result) {
+	if defaults == nil {
+		return
+	}
+
+	for _, k := range diff.SortedKeys(defaults.Added) {
+		log.Info().Str("path", k).Msg("Chart default added in the target version")
+	}
+	for _, k := range diff.SortedKeys(defaults.Modified) {
+		log.Info().Str("path", k).Msg("Chart default changed in the target version")
+	}
+	for _, k := range diff.SortedKeys(defaults.Removed) {
+		log.Info().Str("path", k).Msg("Chart default removed in the target version")
+	}
+}
+
+func scanTemplates(rep *report.Report, baseChart, targetChart *chart.Chart, userPaths []values.Path) {
+	baseTemplates, err := templates.Scan(baseChart)
+	if err != nil {
+		warnTemplateScan(rep, "base", err)
+		return
+	}
+	targetTemplates, err := templates.Scan(targetChart)
+	if err != nil {
+		warnTemplateScan(rep, "target", err)
+		return
+	}
+	reportTemplateMappings(rep, templates.Map(baseTemplates, targetTemplates, userPaths))
+}
+
+func warnTemplateScan(rep *report.Report, version string, err error) {
+	message := fmt.Sprintf("failed to scan %s chart templates, template usage is not checked: %v", version, err)
+	log.Warn().Msg(message)
+	rep.Warn(report.CategoryTemplate, "", message)
+}
+
+func reportTemplateMappings(rep *report.Report, mappings []templates.Mapping) {
+	for _, mapping := range mappings {
+		if !mapping.ReferencedInTarget {
+			if mapping.ReferencedInBase {
+				log.Warn().Str("path", mapping.Path.String()).Msg("Override is no longer referenced by any template in the target version")
+				rep.Warn(report.CategoryTemplate, mapping.Path.String(), "override is no longer referenced by any template in the target version")
+			} else {
+				log.Debug().Str("path", mapping.Path.String()).Msg("Override is not referenced by any template")
+			}
+			continue
+		}
+
+		changed := mapping.ChangedConsumers()
+		if len(changed) == 0 {
+			log.Debug().Str("path", mapping.Path.String()).Msg("Override feeds only unchanged templates")
+			continue
+		}
+
+		names := make([]string, 0, len(changed))
+		for _, consumer := range changed {
+			names = append(names, consumer.Template)
+		}
+		log.Info().Str("path", mapping.Path.String()).Strs("templates", names).Msg("Override feeds templates that changed in the target version")
+		rep.Warn(report.CategoryTemplate, mapping.Path.String(), fmt.Sprintf("override feeds templates that changed in the target version: %s", strings.Join(names, ", ")))
+	}
+}
+
+func reportCRDChanges(rep *report.Report, changes []crd.Change) {
+	for _, change := range changes {
+		event := log.Warn().Str("crd", change.Name).Str("file", change.File)
+
+		var message string
+		switch change.Type {
+		case crd.ChangeAdded:
+			message = "CRD added in the target version, helm does not install CRDs on upgrade, apply it manually"
+		case crd.ChangeRemoved:
+			message = "CRD removed in the target version, helm does not delete CRDs, remove it manually if it is no longer needed"
+		default:
+			message = "CRD changed in the target version, helm does not upgrade CRDs, apply it manually"
+			event = event.Strs("changes", change.Details)
+		}
+		event.Msg(message)
+
+		if len(change.Details) > 0 {
+			message = fmt.Sprintf("%s: %s", message, strings.Join(change.Details, "; "))
+		}
+		rep.Warn(report.CategoryCRD, change.Name, message)
+	}
+}
+
+func reportOrphans(rep *report.Report, orphans []diff.Orphan) {
+	for _, orphan := range orphans {
+		event := log.Warn().Str("path", orphan.Path)
+		if position := orphan.Position.String(); position != "" {
+			event = event.Str("source", position)
+		}
+		message := "value is not defined by the base or target chart version and is ignored by helm"
+		if len(orphan.Suggestions) > 0 {
+			event = event.Strs("did_you_mean", orphan.Suggestions)
+			message = fmt.Sprintf("%s, did you mean %s", message, strings.Join(orphan.Suggestions, ", "))
+		}
+		event.Msg("Value is not defined by the base or target chart version and is ignored by helm")
+		rep.Warn(report.CategoryOrphan, orphan.Path, message)
+	}
+}
+
+func verifyRender(rep *report.Report, cfg *config.Config, baseChart, targetChart *chart.Chart, baseValues, targetValues map[string]interface{}) []error {
+	opts := render.Options{
+		KubeVersion: cfg.KubeVersion,
+		APIVersions: cfg.APIVersions,
+	}
+
+	baseResources, err := render.Render(baseChart, baseValues, opts)
+	if err != nil {
+		return []error{fmt.Errorf("failed to render base chart: %w", err)}
+	}
+
+	targetResources, err := render.Render(targetChart, targetValues, opts)
+	if err != nil {
+		return []error{fmt.Errorf("failed to render target chart: %w", err)}
+	}
+
+	changes := render.Compare(baseResources, targetResources)
+	if len(changes) == 0 {
+		log.Info().Msg("Rendered resources are identical")
+	}
+	for _, change := range changes {
+		log.Info().Str("resource", change.ID).Msgf("Rendered resource %s", change.Type)
+		rep.Warn(report.CategoryRender, change.ID, fmt.Sprintf("rendered resource %s", change.Type))
+		fmt.Fprint(os.Stderr, change.Diff)
+	}
+
+	if len(cfg.AllowedKinds) == 0 {
+		return nil
+	}
+
+	var errors []error
+	for _, change := range render.Disallowed(changes, cfg.AllowedKinds) {
+		errors = append(errors, fmt.Errorf("rendered resource %s was %s, but kind %s is not allowed to change", change.ID, change.Type, change.Kind))
+	}
+	return errors
+}
+
+func applyUpgrades(rep *report.Report, diffResult *diff.Result, userTree *values.Tree, valuesFiles []*values.File, newValuesFile *values.File, overridePaths []values.Path) []error {
 	var errors []error
 
-	for k, v := range diffResult.Added {
-		if err := values.SetValue(userValues, fmt.Sprintf("%v", v), strings.Split(k, ".")...); err != nil {
-			errors = append(errors, fmt.Errorf("failed to set added value %s: %w", k, err))
+	var paths []values.Path
+	changes := make(map[string]diff.ChangeType)
+	nodes := make(map[string]*yaml.Node)
+	for _, changed := range []struct {
+		change diff.ChangeType
+		nodes  map[string]*yaml.Node
+	}{
+		{diff.ChangeAdded, diffResult.Added},
+		{diff.ChangeModified, diffResult.Modified},
+		{diff.ChangeRemoved, diffResult.Removed},
+	} {
+		for _, k := range diff.SortedKeys(changed.nodes) {
+			path, err := values.ParsePath(k)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("failed to parse %s path %s: %w", changed.change, k, err))
+				continue
+			}
+			paths = append(paths, path)
+			changes[path.String()] = changed.change
+			nodes[path.String()] = changed.nodes[k]
 		}
 	}
+	values.SortPaths(paths)
+
+	for _, path := range paths {
+		k := path.String()
+		change, node := changes[k], nodes[k]
 
-	for k, v := range diffResult.Modified {
-		if err := values.SetValue(userValues, fmt.Sprintf("%v", v), strings.Split(k, ".")...); err != nil {
-			errors = append(errors, fmt.Errorf("failed to set modified value %s: %w", k, err))
+		if change == diff.ChangeRemoved {
+			for _, f := range valuesFiles {
+				if _, err := values.Lookup(f.Node, path); err != nil {
+					continue
+				}
+				if err := values.DeletePath(f.Node, path); err != nil {
+					errors = append(errors, fmt.Errorf("failed to delete removed value %s from %s: %w", k, f.Path, err))
+				}
+			}
+			continue
+		}
+
+		if userTree.Contains(node) {
+			continue
+		}
+		if isOverridden(path, overridePaths) {
+			log.Warn().Str("path", k).Msgf("Skipping %s value set by command line overrides", change)
+			rep.Warn(report.CategoryOverride, k, fmt.Sprintf("%s value is set by command line overrides and is not written", change))
+			continue
+		}
+		if err := values.SetNode(ownerOrDefault(valuesFiles, newValuesFile, path).Node, path, values.CopyNode(node)); err != nil {
+			errors = append(errors, fmt.Errorf("failed to set %s value %s: %w", change, k, err))
 		}
 	}
 
-	for k := range diffResult.Removed {
-		if err := values.DeleteValue(userValues, strings.Split(k, ".")...); err != nil {
-			errors = append(errors, fmt.Errorf("failed to delete removed value %s: %w", k, err))
+	return errors
+}
+
+func isOverridden(path values.Path, overridePaths []values.Path) bool {
+	for _, override := range overridePaths {
+		if path.HasPrefix(override) || override.HasPrefix(path) {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(errors) > 0 {
-		return nil, errors
+func ownerOrDefault(valuesFiles []*values.File, newValuesFile *values.File, path values.Path) *values.File {
+	if owner := values.Owner(valuesFiles, path); owner != nil {
+		return owner
 	}
+	return newValuesFile
+}
+
+func printDryRun(cfg *config.Config, valuesFiles []*values.File) error {
+	if cfg.DryRun != config.DryRunDiff {
+		return printUpgradedValues(valuesFiles)
+	}
+
+	color := !cfg.NoColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	for _, f := range values.Distinct(valuesFiles) {
+		var buf bytes.Buffer
+		if err := f.Encode(&buf); err != nil {
+			return err
+		}
 
-	return userValues, nil
+		target := f.Path
+		if !cfg.InPlace {
+			target = cfg.OutputFile
+		}
+
+		unified := textdiff.Unified(f.Path, target, string(f.Raw), buf.String(), cfg.DiffContext)
+		if unified == "" {
+			log.Info().Str("file", f.Path).Msg("No changes")
+			continue
+		}
+		if color {
+			unified = textdiff.Colorize(unified)
+		}
+		fmt.Fprint(os.Stdout, unified)
+	}
+	return nil
 }
 
-func printUpgradedValues(upgradedValues *yaml.Node) error {
-	return values.Write(os.Stdout.Name(), upgradedValues)
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
-func writeOutput(upgradedValues *yaml.Node, outputFile string, inPlace bool, valuesFile string) error {
-	var targetFile string
-	if inPlace {
-		targetFile = valuesFile
-		log.Info().Msg("Updating values file in-place")
-	} else {
-		targetFile = outputFile
+func printUpgradedValues(valuesFiles []*values.File) error {
+	valuesFiles = values.Distinct(valuesFiles)
+	for _, f := range valuesFiles {
+		if len(valuesFiles) > 1 {
+			fmt.Fprintf(os.Stdout, "---\n# Source: %s\n", f.Path)
+		}
+		if err := f.Encode(os.Stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeOutput(valuesFiles []*values.File, outputFile string, inPlace bool) error {
+	if !inPlace {
 		log.Info().Str("file", outputFile).Msg("Writing updated values to new file")
+		if err := valuesFiles[0].Write(outputFile); err != nil {
+			return fmt.Errorf("failed to write updated values: %w", err)
+		}
+		log.Info().Str("file", outputFile).Msg("Successfully wrote updated values")
+		return nil
 	}
 
-	err := values.Write(targetFile, upgradedValues)
-	if err != nil {
-		return fmt.Errorf("failed to write updated values: %w", err)
+	log.Info().Msg("Updating values file in-place")
+	for _, f := range values.Distinct(valuesFiles) {
+		if err := f.Write(f.Path); err != nil {
+			return fmt.Errorf("failed to write updated values to %s: %w", f.Path, err)
+		}
+		log.Info().Str("file", f.Path).Msg("Successfully wrote updated values")
 	}
 
-	log.Info().Str("file", targetFile).Msg("Successfully wrote updated values")
 	return nil
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/diff"
+	"github.com/cstanislawski/helm-valgrade/internal/report"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+const userValues = `image:
+  tag: "1.0"
+legacy:
+  enabled: true
+`
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+func testCharts() (*chart.Chart, *chart.Chart) {
+	base := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "1.1"},
+		"legacy":   map[string]interface{}{"enabled": false, "mode": "a"},
+		"features": map[string]interface{}{},
+	}
+	features := map[string]interface{}{}
+	for i := 0; i < 20; i++ {
+		features[fmt.Sprintf("feature%02d", i)] = false
+	}
+	target := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "2.4"},
+		"features": features,
+	}
+
+	return &chart.Chart{Chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app", Version: "1.0.0"}, Values: base}},
+		&chart.Chart{Chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app", Version: "2.0.0"}, Values: target}}
+}
+
+func upgradeOnce(t *testing.T) (string, string) {
+	t.Helper()
+
+	baseChart, targetChart := testCharts()
+	f, err := values.Parse("values.yaml", []byte(userValues), 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	user := values.FileTree([]*values.File{f})
+
+	result, err := diff.CompareTree(baseChart, targetChart, user, diff.Options{PinDefaults: true})
+	if err != nil {
+		t.Fatalf("CompareTree() error = %v", err)
+	}
+
+	rep := report.New("app", "repo", "1.0.0", "2.0.0", []string{"values.yaml"})
+	rep.AddDecisions(result.Decisions)
+	overrides := []values.Path{values.KeyPath("features", "feature03"), values.KeyPath("features", "feature11")}
+	if errors := applyUpgrades(rep, result, user, []*values.File{f}, f, overrides); len(errors) > 0 {
+		t.Fatalf("applyUpgrades() errors = %v", errors)
+	}
+
+	var out, encoded bytes.Buffer
+	if err := f.Encode(&out); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if err := rep.Encode(&encoded, report.FormatJSON); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return out.String(), encoded.String()
+}
+
+func TestApplyUpgradesIsDeterministic(t *testing.T) {
+	firstValues, firstReport := upgradeOnce(t)
+
+	for i := 0; i < 20; i++ {
+		gotValues, gotReport := upgradeOnce(t)
+		if gotValues != firstValues {
+			t.Fatalf("run %d wrote different values:\n%s\nwant:\n%s", i, gotValues, firstValues)
+		}
+		if gotReport != firstReport {
+			t.Fatalf("run %d produced a different report:\n%s\nwant:\n%s", i, gotReport, firstReport)
+		}
+	}
+
+	expected := "image:\n  tag: \"1.0\"\nfeatures:\n"
+	for i := 0; i < 20; i++ {
+		if i == 3 || i == 11 {
+			continue
+		}
+		expected += fmt.Sprintf("  feature%02d: false\n", i)
+	}
+	if firstValues != expected {
+		t.Errorf("applyUpgrades() wrote\n%s\nwant keys in path order:\n%s", firstValues, expected)
+	}
+}
+
+func TestApplyUpgradesMergesEmbeddedDocuments(t *testing.T) {
+	baseChart := &chart.Chart{Chart: &helmchart.Chart{Values: map[string]interface{}{
+		"config": "route:\n  receiver: \"null\"\n  group_wait: 30s\n",
+	}}}
+	targetChart := &chart.Chart{Chart: &helmchart.Chart{Values: map[string]interface{}{
+		"config": "route:\n  receiver: \"null\"\n  group_wait: 10s\n",
+	}}}
+
+	f, err := values.Parse("values.yaml", []byte("config: |\n  route:\n    receiver: pager # on-call\n    group_wait: 30s\n"), 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	user := values.FileTree([]*values.File{f})
+
+	result, err := diff.CompareTree(baseChart, targetChart, user, diff.Options{})
+	if err != nil {
+		t.Fatalf("CompareTree() error = %v", err)
+	}
+	rep := report.New("app", "repo", "1.0.0", "2.0.0", []string{"values.yaml"})
+	if errors := applyUpgrades(rep, result, user, []*values.File{f}, f, nil); len(errors) > 0 {
+		t.Fatalf("applyUpgrades() errors = %v", errors)
+	}
+
+	var out bytes.Buffer
+	if err := f.Encode(&out); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	expected := "config: |\n  route:\n    receiver: pager # on-call\n    group_wait: 10s\n"
+	if out.String() != expected {
+		t.Errorf("applyUpgrades() wrote\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestApplyUpgradesKeepsTypesAndStyles(t *testing.T) {
+	rawChart := func(version, data string) *chart.Chart {
+		return &chart.Chart{Chart: &helmchart.Chart{
+			Metadata: &helmchart.Metadata{Name: "app", Version: version},
+			Raw:      []*helmchart.File{{Name: "values.yaml", Data: []byte(data)}},
+		}}
+	}
+	baseChart := rawChart("1.0.0", "image:\n  tag: \"1.0\"\nname: app\n")
+	targetChart := rawChart("2.0.0", "image:\n  tag: \"2.0\"\nname: 'app'\nresources:\n  limits:\n    cpu: 1\n")
+
+	f, err := values.Parse("values.yaml", []byte("name: custom # set by us\n"), 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	user := values.FileTree([]*values.File{f})
+
+	result, err := diff.CompareTree(baseChart, targetChart, user, diff.Options{PinDefaults: true})
+	if err != nil {
+		t.Fatalf("CompareTree() error = %v", err)
+	}
+	rep := report.New("app", "repo", "1.0.0", "2.0.0", []string{"values.yaml"})
+	if errors := applyUpgrades(rep, result, user, []*values.File{f}, f, nil); len(errors) > 0 {
+		t.Fatalf("applyUpgrades() errors = %v", errors)
+	}
+
+	var out bytes.Buffer
+	if err := f.Encode(&out); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	expected := "name: custom # set by us\nimage:\n  tag: \"2.0\"\nresources:\n  limits:\n    cpu: 1\n"
+	if out.String() != expected {
+		t.Errorf("applyUpgrades() wrote\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestApplyUpgradesWarnsOnlyForSkippedWrites(t *testing.T) {
+	baseChart := &chart.Chart{Chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app", Version: "1.0.0"}, Values: map[string]interface{}{"replicas": 1}}}
+	targetChart := &chart.Chart{Chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app", Version: "2.0.0"}, Values: map[string]interface{}{"replicas": 2}}}
+
+	tests := []struct {
+		name     string
+		set      int
+		warnings int
+	}{
+		{name: "override differs from the default", set: 5, warnings: 0},
+		{name: "override equals the base default", set: 1, warnings: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := values.Parse("values.yaml", []byte("name: app\n"), 0)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			overrides, err := values.TreeFromMap(map[string]interface{}{"replicas": tt.set}, "--set")
+			if err != nil {
+				t.Fatalf("TreeFromMap() error = %v", err)
+			}
+			user := values.MergeTrees(values.FileTree([]*values.File{f}), overrides)
+
+			result, err := diff.CompareTree(baseChart, targetChart, user, diff.Options{})
+			if err != nil {
+				t.Fatalf("CompareTree() error = %v", err)
+			}
+			rep := report.New("app", "repo", "1.0.0", "2.0.0", []string{"values.yaml"})
+			if errors := applyUpgrades(rep, result, user, []*values.File{f}, f, overrides.LeafPaths()); len(errors) > 0 {
+				t.Fatalf("applyUpgrades() errors = %v", errors)
+			}
+
+			if len(rep.Warnings) != tt.warnings {
+				t.Errorf("Warnings = %+v, want %d", rep.Warnings, tt.warnings)
+			}
+			var out bytes.Buffer
+			if err := f.Encode(&out); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if out.String() != "name: app\n" {
+				t.Errorf("applyUpgrades() wrote\n%s\nwant the file unchanged", out.String())
+			}
+		})
+	}
+}
+
+func TestScanTemplatesWarnsOnParseErrors(t *testing.T) {
+	baseChart, targetChart := testCharts()
+	targetChart.Templates = []*helmchart.File{{Name: "templates/broken.yaml", Data: []byte("replicas: {{ .Values.replicas")}}
+
+	rep := report.New("app", "repo", "1.0.0", "2.0.0", []string{"values.yaml"})
+	scanTemplates(rep, baseChart, targetChart, []values.Path{values.KeyPath("image", "tag")})
+
+	if len(rep.Warnings) != 1 || rep.Warnings[0].Category != report.CategoryTemplate || !strings.Contains(rep.Warnings[0].Message, "failed to scan target chart templates") {
+		t.Errorf("Warnings = %+v, want a template scan warning", rep.Warnings)
+	}
+}
@@ -0,0 +1,228 @@
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	Annotation    = "artifacthub.io/changes"
+	changelogFile = "CHANGELOG.md"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	versionPattern = regexp.MustCompile(`^\[?v?(\d+\.\d+\.\d+(?:[-+][0-9A-Za-z.-]+)?)\]?(?:\s|$|\()`)
+	bulletPattern  = regexp.MustCompile(`^ ?[-*+]\s+(.+)$`)
+)
+
+type Change struct {
+	Kind        string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Description string `json:"description" yaml:"description"`
+}
+
+type Release struct {
+	Version string   `json:"version" yaml:"version"`
+	Changes []Change `json:"changes" yaml:"changes"`
+}
+
+func FromAnnotations(metadata *helmchart.Metadata) (*Release, error) {
+	if metadata == nil || metadata.Annotations[Annotation] == "" {
+		return nil, nil
+	}
+
+	var entries []yaml.Node
+	if err := yaml.Unmarshal([]byte(metadata.Annotations[Annotation]), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation of %s %s: %w", Annotation, metadata.Name, metadata.Version, err)
+	}
+
+	release := &Release{Version: metadata.Version}
+	for _, entry := range entries {
+		var change Change
+		switch entry.Kind {
+		case yaml.ScalarNode:
+			change.Description = entry.Value
+		case yaml.MappingNode:
+			if err := entry.Decode(&change); err != nil {
+				return nil, fmt.Errorf("failed to parse %s annotation of %s %s: %w", Annotation, metadata.Name, metadata.Version, err)
+			}
+		}
+		if change.Description != "" {
+			release.Changes = append(release.Changes, change)
+		}
+	}
+
+	if len(release.Changes) == 0 {
+		return nil, nil
+	}
+	return release, nil
+}
+
+func FromIndex(index *repo.IndexFile, name, base, target string) ([]Release, error) {
+	var releases []Release
+	var errs []error
+	for _, version := range index.Entries[name] {
+		if version.Metadata == nil || !between(version.Version, base, target) {
+			continue
+		}
+		release, err := FromAnnotations(version.Metadata)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if release != nil {
+			releases = append(releases, *release)
+		}
+	}
+
+	sortReleases(releases)
+	return releases, errors.Join(errs...)
+}
+
+func FromChart(c *helmchart.Chart, base, target string) []Release {
+	for _, f := range c.Files {
+		if strings.EqualFold(f.Name, changelogFile) {
+			return FromMarkdown(f.Data, base, target)
+		}
+	}
+	return nil
+}
+
+func FromMarkdown(data []byte, base, target string) []Release {
+	var releases []Release
+	var current *Release
+	level := 0
+	kind := ""
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+
+		if match := headingPattern.FindStringSubmatch(line); match != nil {
+			depth := len(match[1])
+			if version := versionPattern.FindStringSubmatch(match[2]); version != nil && (current == nil || depth <= level) {
+				current, level, kind = nil, depth, ""
+				if between(version[1], base, target) {
+					releases = append(releases, Release{Version: version[1]})
+					current = &releases[len(releases)-1]
+				}
+				continue
+			}
+			if current != nil && depth > level {
+				kind = strings.ToLower(strings.Trim(match[2], "[]* "))
+				continue
+			}
+			if depth <= level {
+				current = nil
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if match := bulletPattern.FindStringSubmatch(line); match != nil {
+			current.Changes = append(current.Changes, Change{Kind: kind, Description: match[1]})
+		}
+	}
+
+	var result []Release
+	for _, release := range releases {
+		if len(release.Changes) > 0 {
+			result = append(result, release)
+		}
+	}
+	sortReleases(result)
+	return result
+}
+
+func Merge(preferred, fallback []Release) []Release {
+	seen := make(map[string]bool, len(preferred))
+	result := append([]Release{}, preferred...)
+	for _, release := range preferred {
+		seen[release.Version] = true
+	}
+	for _, release := range fallback {
+		if !seen[release.Version] {
+			result = append(result, release)
+		}
+	}
+	sortReleases(result)
+	return result
+}
+
+func Related(releases []Release, path string) []string {
+	var related []string
+	for _, release := range releases {
+		for _, change := range release.Changes {
+			if mentions(change.Description, path) {
+				related = append(related, fmt.Sprintf("%s: %s", release.Version, change.Description))
+			}
+		}
+	}
+	return related
+}
+
+func mentions(description, path string) bool {
+	if !strings.Contains(path, ".") {
+		return strings.Contains(description, "`"+path+"`")
+	}
+
+	for offset := 0; offset < len(description); {
+		i := strings.Index(description[offset:], path)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(path)
+		if boundary(description, start-1) && boundary(description, end) {
+			return true
+		}
+		offset = start + 1
+	}
+	return false
+}
+
+func boundary(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return true
+	}
+	if s[i] == '.' {
+		return i+1 == len(s) || s[i+1] == ' '
+	}
+	c := s[i]
+	return !(c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z')
+}
+
+func between(version, base, target string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	b, err := semver.NewVersion(base)
+	if err != nil {
+		return false
+	}
+	t, err := semver.NewVersion(target)
+	if err != nil {
+		return false
+	}
+	return v.GreaterThan(b) && !v.GreaterThan(t)
+}
+
+func sortReleases(releases []Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, errA := semver.NewVersion(releases[i].Version)
+		b, errB := semver.NewVersion(releases[j].Version)
+		if errA != nil || errB != nil {
+			return releases[i].Version < releases[j].Version
+		}
+		return a.LessThan(b)
+	})
+}
@@ -0,0 +1,180 @@
+package changelog
+
+import (
+	"reflect"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		expected   *Release
+		wantErr    bool
+	}{
+		{
+			name:       "no annotation",
+			annotation: "",
+			expected:   nil,
+		},
+		{
+			name:       "plain strings",
+			annotation: "- Bump image to 2.4\n- Add podLabels\n",
+			expected: &Release{Version: "2.0.0", Changes: []Change{
+				{Description: "Bump image to 2.4"},
+				{Description: "Add podLabels"},
+			}},
+		},
+		{
+			name: "structured entries",
+			annotation: `- kind: changed
+  description: Rename service.port to service.ports.http
+  links:
+    - name: PR
+      url: https://example.com/pull/1
+- kind: security
+  description: Fix CVE-2024-0001
+`,
+			expected: &Release{Version: "2.0.0", Changes: []Change{
+				{Kind: "changed", Description: "Rename service.port to service.ports.http"},
+				{Kind: "security", Description: "Fix CVE-2024-0001"},
+			}},
+		},
+		{
+			name:       "invalid",
+			annotation: "kind: changed",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := &helmchart.Metadata{Name: "app", Version: "2.0.0", Annotations: map[string]string{Annotation: tt.annotation}}
+
+			got, err := FromAnnotations(metadata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromAnnotations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("FromAnnotations() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFromIndex(t *testing.T) {
+	index := repo.NewIndexFile()
+	for version, annotation := range map[string]string{
+		"1.0.0": "- Initial release",
+		"1.1.0": "- Add podLabels",
+		"1.2.0": "kind: broken",
+		"2.0.0": "- kind: changed\n  description: Rename service.port to service.ports.http",
+		"2.1.0": "- Not part of the upgrade",
+	} {
+		index.Entries["app"] = append(index.Entries["app"], &repo.ChartVersion{
+			Metadata: &helmchart.Metadata{Name: "app", Version: version, Annotations: map[string]string{Annotation: annotation}},
+		})
+	}
+
+	got, err := FromIndex(index, "app", "1.0.0", "2.0.0")
+	if err == nil {
+		t.Error("FromIndex() did not report the invalid annotation")
+	}
+
+	expected := []Release{
+		{Version: "1.1.0", Changes: []Change{{Description: "Add podLabels"}}},
+		{Version: "2.0.0", Changes: []Change{{Kind: "changed", Description: "Rename service.port to service.ports.http"}}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("FromIndex() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestFromMarkdown(t *testing.T) {
+	data := `# Changelog
+
+## [Unreleased]
+
+- Not released yet
+
+## [2.0.0] - 2024-05-01
+
+### Changed
+
+- Rename ` + "`service.port`" + ` to ` + "`service.ports.http`" + `
+  with a continuation line
+
+### Removed
+
+* Drop ` + "`legacy`" + `
+
+## v1.1.0
+
+- Add podLabels
+  - nested detail
+
+## 1.0.0
+
+- Initial release
+`
+
+	expected := []Release{
+		{Version: "1.1.0", Changes: []Change{{Description: "Add podLabels"}}},
+		{Version: "2.0.0", Changes: []Change{
+			{Kind: "changed", Description: "Rename `service.port` to `service.ports.http`"},
+			{Kind: "removed", Description: "Drop `legacy`"},
+		}},
+	}
+	if got := FromMarkdown([]byte(data), "1.0.0", "2.0.0"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("FromMarkdown() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	preferred := []Release{{Version: "2.0.0", Changes: []Change{{Description: "from annotations"}}}}
+	fallback := []Release{
+		{Version: "1.10.0", Changes: []Change{{Description: "from CHANGELOG.md"}}},
+		{Version: "2.0.0", Changes: []Change{{Description: "from CHANGELOG.md"}}},
+	}
+
+	expected := []Release{
+		{Version: "1.10.0", Changes: []Change{{Description: "from CHANGELOG.md"}}},
+		{Version: "2.0.0", Changes: []Change{{Description: "from annotations"}}},
+	}
+	if got := Merge(preferred, fallback); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Merge() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	releases := []Release{
+		{Version: "1.1.0", Changes: []Change{{Description: "Add `replicas` validation"}}},
+		{Version: "2.0.0", Changes: []Change{
+			{Description: "Rename service.port to service.ports.http"},
+			{Description: "Default service.portName changed."},
+			{Description: "Bump replicas for HA"},
+		}},
+	}
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{path: "service.port", expected: []string{"2.0.0: Rename service.port to service.ports.http"}},
+		{path: "service.portName", expected: []string{"2.0.0: Default service.portName changed."}},
+		{path: "service.ports.http", expected: []string{"2.0.0: Rename service.port to service.ports.http"}},
+		{path: "replicas", expected: []string{"1.1.0: Add `replicas` validation"}},
+		{path: "ports.http", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Related(releases, tt.path); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Related() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
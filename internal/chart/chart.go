@@ -3,14 +3,17 @@ package chart
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/cstanislawski/helm-valgrade/internal/values"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
@@ -59,6 +62,79 @@ func (c *Chart) GetDefaultValues() map[string]interface{} {
 	return c.Values
 }
 
+func (c *Chart) GetDefaultValuesTree() (*values.Tree, error) {
+	for _, f := range c.Raw {
+		if f.Name != "values.yaml" {
+			continue
+		}
+		parsed, err := values.Parse(f.Name, f.Data, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse values.yaml of %s: %w", c.Name(), err)
+		}
+		return values.NewTree(parsed.Node, valuesFile(c.Chart)), nil
+	}
+
+	return values.TreeFromMap(c.Values, "")
+}
+
+func (c *Chart) GetAllDefaultValues() map[string]interface{} {
+	return allDefaultValues(c.Chart)
+}
+
+func (c *Chart) GetAllDefaultValuesTree() (*values.Tree, error) {
+	tree, err := c.GetDefaultValuesTree()
+	if err != nil {
+		return nil, err
+	}
+
+	var trees []*values.Tree
+	for _, dependency := range c.Dependencies() {
+		dependencyTree, err := (&Chart{Chart: dependency}).GetAllDefaultValuesTree()
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, values.NestTree(DependencyKey(c.Chart, dependency), dependencyTree))
+	}
+
+	return values.MergeTrees(append(trees, tree)...), nil
+}
+
+func valuesFile(c *chart.Chart) string {
+	if c.Metadata == nil {
+		return "values.yaml"
+	}
+	return fmt.Sprintf("%s-%s/values.yaml", c.Metadata.Name, c.Metadata.Version)
+}
+
+func allDefaultValues(c *chart.Chart) map[string]interface{} {
+	result := make(map[string]interface{}, len(c.Values))
+	for k, v := range c.Values {
+		result[k] = v
+	}
+
+	for _, dependency := range c.Dependencies() {
+		key := DependencyKey(c, dependency)
+		dependencyValues := allDefaultValues(dependency)
+		if parentValues, ok := result[key].(map[string]interface{}); ok {
+			dependencyValues = values.MergeMaps(dependencyValues, parentValues)
+		}
+		result[key] = dependencyValues
+	}
+
+	return result
+}
+
+func DependencyKey(parent, dependency *chart.Chart) string {
+	if parent.Metadata != nil {
+		for _, d := range parent.Metadata.Dependencies {
+			if d.Name == dependency.Name() && d.Alias != "" {
+				return d.Alias
+			}
+		}
+	}
+	return dependency.Name()
+}
+
 func (c *Chart) GetSchema() []byte {
 	return c.Schema
 }
@@ -104,6 +180,36 @@ func UpdateRepository(name, url string, settings *cli.EnvSettings) error {
 	return nil
 }
 
+func FetchIndex(repository string) (*repo.IndexFile, error) {
+	settings := cli.New()
+
+	if !strings.Contains(repository, "://") {
+		return repo.LoadIndexFile(filepath.Join(settings.RepositoryCache, helmpath.CacheIndexFile(repository)))
+	}
+	if strings.HasPrefix(repository, "oci://") {
+		return nil, fmt.Errorf("OCI repository %s has no index", repository)
+	}
+
+	r, err := repo.NewChartRepository(&repo.Entry{URL: repository}, getter.All(settings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chart repository: %w", err)
+	}
+
+	cache, err := os.MkdirTemp("", "valgrade-index-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index cache: %w", err)
+	}
+	defer os.RemoveAll(cache)
+	r.CachePath = cache
+
+	filename, err := r.DownloadIndexFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to download repository index: %w", err)
+	}
+
+	return repo.LoadIndexFile(filename)
+}
+
 func logf(format string, v ...interface{}) {
 	fmt.Printf(format, v...)
 }
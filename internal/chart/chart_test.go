@@ -2,8 +2,12 @@ package chart
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/cstanislawski/helm-valgrade/internal/values"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/repo"
@@ -99,6 +103,115 @@ func TestChartMethods(t *testing.T) {
 	})
 }
 
+func TestGetAllDefaultValues(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "grafana"},
+		Values:   map[string]interface{}{"adminUser": "admin", "replicas": 1},
+	}
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:         "stack",
+			Dependencies: []*chart.Dependency{{Name: "grafana", Alias: "dashboards"}},
+		},
+		Values: map[string]interface{}{
+			"key":        "value",
+			"dashboards": map[string]interface{}{"replicas": 2},
+		},
+	}
+	parent.SetDependencies(subchart)
+
+	c := &Chart{Chart: parent}
+	got := c.GetAllDefaultValues()
+
+	expected := map[string]interface{}{
+		"key":        "value",
+		"dashboards": map[string]interface{}{"adminUser": "admin", "replicas": 2},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetAllDefaultValues() = %v, want %v", got, expected)
+	}
+}
+
+func TestGetAllDefaultValuesTree(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "grafana", Version: "8.0.0"},
+		Values:   map[string]interface{}{"adminUser": "admin", "replicas": 1},
+		Raw:      []*chart.File{{Name: "values.yaml", Data: []byte("adminUser: admin\nreplicas: 1\n")}},
+	}
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:         "stack",
+			Version:      "1.0.0",
+			Dependencies: []*chart.Dependency{{Name: "grafana", Alias: "dashboards"}},
+		},
+		Values: map[string]interface{}{
+			"key":        "value",
+			"dashboards": map[string]interface{}{"replicas": 2},
+		},
+		Raw: []*chart.File{{Name: "values.yaml", Data: []byte("key: value\ndashboards:\n  replicas: 2\n")}},
+	}
+	parent.SetDependencies(subchart)
+
+	c := &Chart{Chart: parent}
+	tree, err := c.GetAllDefaultValuesTree()
+	if err != nil {
+		t.Fatalf("GetAllDefaultValuesTree() error = %v", err)
+	}
+
+	got, err := tree.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := c.GetAllDefaultValues(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetAllDefaultValuesTree() = %v, want %v", got, expected)
+	}
+
+	for path, position := range map[string]string{
+		"dashboards.adminUser": "grafana-8.0.0/values.yaml:1:12",
+		"dashboards.replicas":  "stack-1.0.0/values.yaml:3:13",
+	} {
+		node, ok := tree.Lookup(values.KeyPath(strings.Split(path, ".")...))
+		if !ok {
+			t.Errorf("Lookup(%s) not found", path)
+			continue
+		}
+		if got := tree.Position(node).String(); got != position {
+			t.Errorf("Position(%s) = %q, want %q", path, got, position)
+		}
+	}
+}
+
+func TestGetDefaultValuesTree(t *testing.T) {
+	raw := &Chart{Chart: &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app", Version: "2.0.0"},
+		Values:   map[string]interface{}{"image": map[string]interface{}{"tag": "1.0"}},
+		Raw:      []*chart.File{{Name: "values.yaml", Data: []byte("# image settings\nimage:\n  tag: \"1.0\"\n")}},
+	}}
+	tree, err := raw.GetDefaultValuesTree()
+	if err != nil {
+		t.Fatalf("GetDefaultValuesTree() error = %v", err)
+	}
+	node, ok := tree.Lookup(values.KeyPath("image", "tag"))
+	if !ok || node.Value != "1.0" {
+		t.Fatalf("Lookup(image.tag) = %v, %v, want 1.0", node, ok)
+	}
+	if got := tree.Position(node).String(); got != "app-2.0.0/values.yaml:3:8" {
+		t.Errorf("Position(image.tag) = %q, want app-2.0.0/values.yaml:3:8", got)
+	}
+
+	decoded, err := (&Chart{Chart: &chart.Chart{Values: raw.Values}}).GetDefaultValuesTree()
+	if err != nil {
+		t.Fatalf("GetDefaultValuesTree() without raw values error = %v", err)
+	}
+	got, err := decoded.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, raw.Values) {
+		t.Errorf("GetDefaultValuesTree() without raw values = %v, want %v", got, raw.Values)
+	}
+}
+
 type mockRepoFile struct {
 	repositories []*repo.Entry
 }
@@ -177,3 +290,28 @@ func updateRepositoryWithMock(name, url string, mockRepo *mockRepoFile) error {
 	mockRepo.Update(repoEntry)
 	return nil
 }
+
+func TestFetchIndex(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("HELM_REPOSITORY_CACHE", cache)
+
+	index := repo.NewIndexFile()
+	if err := index.MustAdd(&chart.Metadata{APIVersion: chart.APIVersionV2, Name: testChartName, Version: testVersion}, testChartName+"-"+testVersion+".tgz", testRepositoryURL, ""); err != nil {
+		t.Fatalf("Failed to build index: %v", err)
+	}
+	if err := index.WriteFile(filepath.Join(cache, testRepositoryName+"-index.yaml"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	loaded, err := FetchIndex(testRepositoryName)
+	if err != nil {
+		t.Fatalf("FetchIndex() error = %v", err)
+	}
+	if !loaded.Has(testChartName, testVersion) {
+		t.Errorf("FetchIndex() did not load %s %s from the repository cache", testChartName, testVersion)
+	}
+
+	if _, err := FetchIndex("oci://registry.example.com/charts"); err == nil {
+		t.Error("FetchIndex() did not fail for an OCI repository")
+	}
+}
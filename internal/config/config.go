@@ -3,59 +3,145 @@ package config
 import (
 	"flag"
 	"fmt"
+	"os"
 	"strings"
+
+	"github.com/cstanislawski/helm-valgrade/internal/report"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+const (
+	CommandUpgrade  = "upgrade"
+	CommandMinimize = "minimize"
+	CommandCheck    = "check"
+	CommandExplain  = "explain"
+)
+
+var commands = []string{CommandUpgrade, CommandMinimize, CommandCheck, CommandExplain}
+
+const (
+	DryRunFull = "full"
+	DryRunDiff = "diff"
 )
 
+var dryRunModes = []string{DryRunFull, DryRunDiff}
+
 type Config struct {
-	VersionBase   string
-	VersionTarget string
-	ValuesFile    string
-	OutputFile    string
-	InPlace       bool
-	Repository    string
-	ChartName     string
-	KeepValues    []string
-	Silent        bool
-	LogLevel      string
-	DryRun        bool
-	IgnoreMissing bool
-	Help          bool
+	Command              string
+	ExplainPath          string
+	VersionBase          string
+	VersionTarget        string
+	ValuesFiles          []string
+	NewValuesFile        string
+	Document             int
+	OutputFile           string
+	InPlace              bool
+	Repository           string
+	ChartName            string
+	KeepValues           []string
+	Overrides            values.Overrides
+	Silent               bool
+	LogLevel             string
+	DryRun               string
+	DiffContext          int
+	NoColor              bool
+	IgnoreMissing        bool
+	PinDefaults          bool
+	PinUserValues        bool
+	CaseInsensitiveBools bool
+	EmbeddedPaths        []string
+	KeepPinned           bool
+	SkipSchemaValidation bool
+	VerifyRender         bool
+	KubeVersion          string
+	APIVersions          []string
+	AllowedKinds         []string
+	Strict               bool
+	ReportFormat         string
+	ReportFile           string
+	Help                 bool
+
+	extraArgs []string
 }
 
 func Parse() (*Config, error) {
 	cfg := &Config{
+		Command:  CommandUpgrade,
 		LogLevel: "info",
 	}
 
-	flag.Usage = PrintHelp
-
-	flag.StringVar(&cfg.VersionBase, "version-base", "", "")
-	flag.StringVar(&cfg.VersionBase, "b", "", "")
-	flag.StringVar(&cfg.VersionTarget, "version-target", "", "")
-	flag.StringVar(&cfg.VersionTarget, "t", "", "")
-	flag.StringVar(&cfg.ValuesFile, "values", "", "")
-	flag.StringVar(&cfg.ValuesFile, "f", "", "")
-	flag.StringVar(&cfg.OutputFile, "output-file", "", "")
-	flag.StringVar(&cfg.OutputFile, "o", "", "")
-	flag.BoolVar(&cfg.InPlace, "in-place", false, "")
-	flag.BoolVar(&cfg.InPlace, "i", false, "")
-	flag.StringVar(&cfg.Repository, "repository", "", "")
-	flag.StringVar(&cfg.Repository, "r", "", "")
-	flag.StringVar(&cfg.ChartName, "chart", "", "")
-	flag.StringVar(&cfg.ChartName, "c", "", "")
-	flag.Var((*stringSliceFlag)(&cfg.KeepValues), "keep", "")
-	flag.Var((*stringSliceFlag)(&cfg.KeepValues), "k", "")
-	flag.BoolVar(&cfg.Silent, "silent", false, "")
-	flag.BoolVar(&cfg.Silent, "s", false, "")
-	flag.StringVar(&cfg.LogLevel, "log-level", "info", "")
-	flag.StringVar(&cfg.LogLevel, "l", "info", "")
-	flag.BoolVar(&cfg.DryRun, "dry-run", false, "")
-	flag.BoolVar(&cfg.DryRun, "d", false, "")
-	flag.BoolVar(&cfg.IgnoreMissing, "ignore-missing", false, "")
-	flag.BoolVar(&cfg.Help, "help", false, "")
-	flag.BoolVar(&cfg.Help, "h", false, "")
-
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 && contains(commands, args[0]) {
+		cfg.Command = args[0]
+		args = args[1:]
+	}
+	if cfg.Command == CommandExplain && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cfg.ExplainPath = args[0]
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.Usage = PrintHelp
+
+	flags.StringVar(&cfg.VersionBase, "version-base", "", "")
+	flags.StringVar(&cfg.VersionBase, "b", "", "")
+	flags.StringVar(&cfg.VersionTarget, "version-target", "", "")
+	flags.StringVar(&cfg.VersionTarget, "t", "", "")
+	flags.Var((*stringSliceFlag)(&cfg.ValuesFiles), "values", "")
+	flags.Var((*stringSliceFlag)(&cfg.ValuesFiles), "f", "")
+	flags.StringVar(&cfg.NewValuesFile, "new-values-file", "", "")
+	flags.IntVar(&cfg.Document, "document", 0, "")
+	flags.StringVar(&cfg.OutputFile, "output-file", "", "")
+	flags.StringVar(&cfg.OutputFile, "o", "", "")
+	flags.BoolVar(&cfg.InPlace, "in-place", false, "")
+	flags.BoolVar(&cfg.InPlace, "i", false, "")
+	flags.StringVar(&cfg.Repository, "repository", "", "")
+	flags.StringVar(&cfg.Repository, "r", "", "")
+	flags.StringVar(&cfg.ChartName, "chart", "", "")
+	flags.StringVar(&cfg.ChartName, "c", "", "")
+	flags.Var((*stringSliceFlag)(&cfg.KeepValues), "keep", "")
+	flags.Var((*stringSliceFlag)(&cfg.KeepValues), "k", "")
+	flags.Var((*stringArrayFlag)(&cfg.Overrides.Values), "set", "")
+	flags.Var((*stringArrayFlag)(&cfg.Overrides.StringValues), "set-string", "")
+	flags.Var((*stringArrayFlag)(&cfg.Overrides.JSONValues), "set-json", "")
+	flags.Var((*stringArrayFlag)(&cfg.Overrides.FileValues), "set-file", "")
+	flags.BoolVar(&cfg.Silent, "silent", false, "")
+	flags.BoolVar(&cfg.Silent, "s", false, "")
+	flags.StringVar(&cfg.LogLevel, "log-level", "info", "")
+	flags.StringVar(&cfg.LogLevel, "l", "info", "")
+	flags.Var((*dryRunFlag)(&cfg.DryRun), "dry-run", "")
+	flags.Var((*dryRunFlag)(&cfg.DryRun), "d", "")
+	flags.IntVar(&cfg.DiffContext, "diff-context", 3, "")
+	flags.BoolVar(&cfg.NoColor, "no-color", false, "")
+	flags.BoolVar(&cfg.IgnoreMissing, "ignore-missing", false, "")
+	flags.BoolVar(&cfg.PinDefaults, "pin-defaults", false, "")
+	flags.BoolVar(&cfg.PinUserValues, "pin-user-values", false, "")
+	flags.BoolVar(&cfg.CaseInsensitiveBools, "case-insensitive-bools", false, "")
+	flags.Var((*stringSliceFlag)(&cfg.EmbeddedPaths), "embedded", "")
+	flags.BoolVar(&cfg.KeepPinned, "keep-pinned", false, "")
+	flags.BoolVar(&cfg.SkipSchemaValidation, "skip-schema-validation", false, "")
+	flags.BoolVar(&cfg.VerifyRender, "verify-render", false, "")
+	flags.StringVar(&cfg.KubeVersion, "kube-version", "", "")
+	flags.Var((*stringSliceFlag)(&cfg.APIVersions), "api-versions", "")
+	flags.Var((*stringSliceFlag)(&cfg.AllowedKinds), "allowed-kinds", "")
+	flags.BoolVar(&cfg.Strict, "strict", false, "")
+	flags.StringVar(&cfg.ReportFormat, "report-format", "", "")
+	flags.StringVar(&cfg.ReportFile, "report-file", "", "")
+	flags.BoolVar(&cfg.Help, "help", false, "")
+	flags.BoolVar(&cfg.Help, "h", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg.extraArgs = flags.Args()
+	if cfg.Command == CommandExplain && cfg.ExplainPath == "" && len(cfg.extraArgs) > 0 {
+		cfg.ExplainPath = cfg.extraArgs[0]
+		cfg.extraArgs = cfg.extraArgs[1:]
+	}
+	if cfg.ReportFile != "" && cfg.ReportFormat == "" {
+		cfg.ReportFormat = string(report.FormatFromFilename(cfg.ReportFile))
+	}
 
 	return cfg, cfg.validate()
 }
@@ -67,21 +153,54 @@ func (cfg *Config) validate() error {
 
 	var errors []string
 
+	if len(cfg.extraArgs) > 0 {
+		errors = append(errors, fmt.Sprintf("unexpected argument %q, flags after it were not parsed (use --dry-run=diff instead of --dry-run diff)", cfg.extraArgs[0]))
+	}
 	if cfg.VersionBase == "" {
 		errors = append(errors, "version-base is required (use -b or --version-base)")
 	}
-	if cfg.VersionTarget == "" {
+	if cfg.VersionTarget == "" && cfg.Command != CommandMinimize {
 		errors = append(errors, "version-target is required (use -t or --version-target)")
 	}
-	if cfg.ValuesFile == "" {
+	if len(cfg.ValuesFiles) == 0 {
 		errors = append(errors, "values file is required (use -f or --values)")
 	}
-	if !cfg.InPlace && cfg.OutputFile == "" {
+	if cfg.NewValuesFile != "" && !contains(cfg.ValuesFiles, cfg.NewValuesFile) {
+		errors = append(errors, "new-values-file must be one of the values files passed with -f or --values")
+	}
+	if cfg.Document < 0 {
+		errors = append(errors, "document index must not be negative")
+	}
+	if cfg.DryRun != "" && !contains(dryRunModes, cfg.DryRun) {
+		errors = append(errors, "dry-run must be one of full, diff")
+	}
+	if cfg.DiffContext < 0 {
+		errors = append(errors, "diff-context must not be negative")
+	}
+	if cfg.Command == CommandExplain {
+		if cfg.ExplainPath == "" {
+			errors = append(errors, "explain requires a path, e.g. helm valgrade explain image.tag")
+		} else if _, err := values.ParsePath(cfg.ExplainPath); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if !cfg.InPlace && cfg.OutputFile == "" && cfg.Command != CommandCheck && cfg.Command != CommandExplain {
 		errors = append(errors, "either in-place (-i) or output-file (-o) must be specified")
 	}
 	if cfg.InPlace && cfg.OutputFile != "" {
 		return fmt.Errorf("in-place and output-file cannot be used together")
 	}
+	if len(cfg.ValuesFiles) > 1 && cfg.OutputFile != "" {
+		return fmt.Errorf("output-file cannot be used with multiple values files, use in-place instead")
+	}
+	if cfg.ReportFormat != "" {
+		if _, err := report.ParseFormat(cfg.ReportFormat); err != nil {
+			errors = append(errors, "report-format must be one of json, yaml, markdown")
+		}
+		if cfg.ReportFile == "" && cfg.DryRun != "" {
+			errors = append(errors, "report-file is required with dry-run, the report would mix with the dry-run output on stdout")
+		}
+	}
 	if cfg.Repository == "" {
 		errors = append(errors, "repository is required (use -r or --repository)")
 	}
@@ -96,6 +215,15 @@ func (cfg *Config) validate() error {
 	return nil
 }
 
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if filename, _, _ := values.SplitDocument(item); item == value || filename == value {
+			return true
+		}
+	}
+	return false
+}
+
 type stringSliceFlag []string
 
 func (s *stringSliceFlag) String() string {
@@ -107,20 +235,79 @@ func (s *stringSliceFlag) Set(value string) error {
 	return nil
 }
 
+type dryRunFlag string
+
+func (d *dryRunFlag) String() string {
+	return string(*d)
+}
+
+func (d *dryRunFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (d *dryRunFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*d = DryRunFull
+	case "false":
+		*d = ""
+	default:
+		*d = dryRunFlag(value)
+	}
+	return nil
+}
+
+type stringArrayFlag []string
+
+func (s *stringArrayFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringArrayFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func PrintHelp() {
-	fmt.Println("Usage: helm valgrade [flags]")
+	fmt.Println("Usage: helm valgrade [command] [path] [flags]")
+	fmt.Println("\nCommands:")
+	fmt.Println("  upgrade                      Upgrade the values file from the base to the target chart version (default)")
+	fmt.Println("  minimize                     Remove overrides that are equal to the defaults of the base chart version")
+	fmt.Println("  check                        Check whether the values files are up to date with the target chart version without writing")
+	fmt.Println("  explain <path>               Explain the upgrade decision for a single path without writing")
 	fmt.Println("\nFlags:")
-	fmt.Println("  -b, --version-base string    The version of the chart you are upgrading from")
+	fmt.Println("  -b, --version-base string    The version of the chart you are upgrading from or minimizing against")
 	fmt.Println("  -t, --version-target string  The version of the chart you are upgrading to")
-	fmt.Println("  -f, --values string          The path to the values file you are using")
+	fmt.Println("  -f, --values string          The path to the values file you are using (can be repeated, later files take precedence)")
+	fmt.Println("      --new-values-file string The values file that receives newly added keys (default: the first values file)")
+	fmt.Println("      --document int           The index of the document to upgrade in multi-document values files, or file.yaml#index per file (default 0)")
 	fmt.Println("  -o, --output-file string     The path to the output file")
 	fmt.Println("  -i, --in-place               Update the values file in place")
 	fmt.Println("  -r, --repository string      The repository where the chart is located")
 	fmt.Println("  -c, --chart string           The name of the chart")
 	fmt.Println("  -k, --keep string            Exclude specific values from the upgrade process (comma-separated)")
+	fmt.Println("      --set stringArray        Set values on the command line like helm (can be repeated)")
+	fmt.Println("      --set-string stringArray Set STRING values on the command line like helm (can be repeated)")
+	fmt.Println("      --set-json stringArray   Set JSON values on the command line like helm (can be repeated)")
+	fmt.Println("      --set-file stringArray   Set values from files on the command line like helm (can be repeated)")
 	fmt.Println("  -s, --silent                 Suppress all output")
 	fmt.Println("  -l, --log-level string       Set the log level (debug, info, warn, error, fatal) (default \"info\")")
-	fmt.Println("  -d, --dry-run                Print the result without writing to the output file")
+	fmt.Println("  -d, --dry-run[=mode]         Print the result without writing to the output file (full, diff) (default \"full\")")
+	fmt.Println("      --diff-context int       Lines of context around changes with --dry-run=diff (default 3)")
+	fmt.Println("      --no-color               Do not color the output of --dry-run=diff")
 	fmt.Println("      --ignore-missing         Ignore missing values in the old chart version")
+	fmt.Println("      --pin-defaults           Also write changed chart defaults into the values file")
+	fmt.Println("      --pin-user-values        Treat every key present in the values files as intentional, even when equal to the base default")
+	fmt.Println("      --case-insensitive-bools Treat boolean strings like \"True\" as equal to true when comparing values")
+	fmt.Println("      --embedded string        Merge string values at these paths as embedded YAML/JSON documents (comma-separated)")
+	fmt.Println("      --keep-pinned            Do not minimize values marked with a '# valgrade:pin' comment")
+	fmt.Println("      --skip-schema-validation Write the upgraded values even if they do not match the target values.schema.json")
+	fmt.Println("      --verify-render          Render both chart versions offline and print the diff of the rendered resources")
+	fmt.Println("      --kube-version string    The kubernetes version to check the chart's kubeVersion against and to render with")
+	fmt.Println("      --api-versions string    Additional API versions available for rendering (comma-separated)")
+	fmt.Println("      --allowed-kinds string   Fail --verify-render if resources of other kinds change (comma-separated)")
+	fmt.Println("      --strict                 Treat preflight warnings as errors")
+	fmt.Println("      --report-format string   Emit an upgrade report (json, yaml, markdown)")
+	fmt.Println("      --report-file string     Write the report to a file instead of stdout (format detected from the extension)")
 	fmt.Println("  -h, --help                   Display this help message")
 }
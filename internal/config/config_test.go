@@ -1,17 +1,11 @@
 package config
 
 import (
-	"flag"
 	"os"
 	"testing"
 )
 
-func resetFlags() {
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-}
-
 func TestParse_ValidInput(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -35,8 +29,8 @@ func TestParse_ValidInput(t *testing.T) {
 		t.Errorf("Expected version-target '2.0.0', got '%s'", cfg.VersionTarget)
 	}
 
-	if cfg.ValuesFile != "test.yaml" {
-		t.Errorf("Expected values file 'test.yaml', got '%s'", cfg.ValuesFile)
+	if len(cfg.ValuesFiles) != 1 || cfg.ValuesFiles[0] != "test.yaml" {
+		t.Errorf("Expected values files ['test.yaml'], got '%v'", cfg.ValuesFiles)
 	}
 
 	if cfg.OutputFile != "result.yaml" {
@@ -53,7 +47,6 @@ func TestParse_ValidInput(t *testing.T) {
 }
 
 func TestParse_InPlaceFlag(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -79,7 +72,6 @@ func TestParse_InPlaceFlag(t *testing.T) {
 }
 
 func TestParse_MissingRequiredFlags(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -100,7 +92,6 @@ func TestParse_MissingRequiredFlags(t *testing.T) {
 }
 
 func TestParse_InPlaceAndOutputFileTogether(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -124,7 +115,6 @@ func TestParse_InPlaceAndOutputFileTogether(t *testing.T) {
 }
 
 func TestParse_KeepValues(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -150,7 +140,6 @@ func TestParse_KeepValues(t *testing.T) {
 }
 
 func TestParse_DefaultLogLevel(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -172,7 +161,6 @@ func TestParse_DefaultLogLevel(t *testing.T) {
 }
 
 func TestParse_CustomLogLevel(t *testing.T) {
-	resetFlags()
 	os.Args = []string{
 		"cmd",
 		"--version-base=1.0.0",
@@ -193,3 +181,527 @@ func TestParse_CustomLogLevel(t *testing.T) {
 		t.Errorf("Expected log level 'debug', got '%s'", cfg.LogLevel)
 	}
 }
+
+func TestParse_MultipleValuesFiles(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"-f", "values.yaml",
+		"-f", "values-prod.yaml",
+		"--values=values-secrets.yaml",
+		"--new-values-file=values-prod.yaml",
+		"--in-place",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+	}
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedValuesFiles := []string{"values.yaml", "values-prod.yaml", "values-secrets.yaml"}
+	if len(cfg.ValuesFiles) != len(expectedValuesFiles) {
+		t.Fatalf("Expected %d values files, got %v", len(expectedValuesFiles), cfg.ValuesFiles)
+	}
+	for i, value := range expectedValuesFiles {
+		if cfg.ValuesFiles[i] != value {
+			t.Errorf("Expected ValuesFiles[%d] to be '%s', got '%s'", i, value, cfg.ValuesFiles[i])
+		}
+	}
+
+	if cfg.NewValuesFile != "values-prod.yaml" {
+		t.Errorf("Expected new values file 'values-prod.yaml', got '%s'", cfg.NewValuesFile)
+	}
+}
+
+func TestParse_MultipleValuesFilesWithOutputFile(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"-f", "values.yaml",
+		"-f", "values-prod.yaml",
+		"--output-file=result.yaml",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+	}
+
+	_, err := Parse()
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	expectedError := "output-file cannot be used with multiple values files, use in-place instead"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
+	}
+}
+
+func TestParse_UnknownNewValuesFile(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"-f", "values.yaml",
+		"--new-values-file=other.yaml",
+		"--in-place",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+	}
+
+	_, err := Parse()
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	expectedError := "invalid configuration: new-values-file must be one of the values files passed with -f or --values"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
+	}
+}
+
+func TestParse_Overrides(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"--values=test.yaml",
+		"--output-file=result.yaml",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+		"--set=a=1,b=2",
+		"--set=c=3",
+		"--set-string=d=4",
+		"--set-json=e={\"f\":1}",
+		"--set-file=g=config.txt",
+	}
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(cfg.Overrides.Values) != 2 || cfg.Overrides.Values[0] != "a=1,b=2" || cfg.Overrides.Values[1] != "c=3" {
+		t.Errorf("Expected set values [a=1,b=2 c=3], got %v", cfg.Overrides.Values)
+	}
+	if len(cfg.Overrides.StringValues) != 1 || cfg.Overrides.StringValues[0] != "d=4" {
+		t.Errorf("Expected set-string values [d=4], got %v", cfg.Overrides.StringValues)
+	}
+	if len(cfg.Overrides.JSONValues) != 1 || cfg.Overrides.JSONValues[0] != `e={"f":1}` {
+		t.Errorf("Expected set-json values [e={\"f\":1}], got %v", cfg.Overrides.JSONValues)
+	}
+	if len(cfg.Overrides.FileValues) != 1 || cfg.Overrides.FileValues[0] != "g=config.txt" {
+		t.Errorf("Expected set-file values [g=config.txt], got %v", cfg.Overrides.FileValues)
+	}
+}
+
+func TestParse_Document(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"--values=test.yaml",
+		"--document=2",
+		"--output-file=result.yaml",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+	}
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.Document != 2 {
+		t.Errorf("Expected document 2, got %d", cfg.Document)
+	}
+}
+
+func TestParse_PinDefaults(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"--values=test.yaml",
+		"--output-file=result.yaml",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+	}
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.PinDefaults {
+		t.Errorf("Expected pin-defaults to be false by default, got true")
+	}
+
+	os.Args = append(os.Args, "--pin-defaults")
+
+	cfg, err = Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !cfg.PinDefaults {
+		t.Errorf("Expected pin-defaults to be true, got false")
+	}
+}
+
+func TestParse_Commands(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedCommand string
+		expectedError   string
+	}{
+		{
+			name:            "Default command",
+			args:            []string{"--version-base=1.0.0", "--version-target=2.0.0"},
+			expectedCommand: CommandUpgrade,
+		},
+		{
+			name:            "Explicit upgrade command",
+			args:            []string{"upgrade", "--version-base=1.0.0", "--version-target=2.0.0"},
+			expectedCommand: CommandUpgrade,
+		},
+		{
+			name:            "Minimize without target version",
+			args:            []string{"minimize", "--version-base=1.0.0", "--keep-pinned"},
+			expectedCommand: CommandMinimize,
+		},
+		{
+			name:            "Check command",
+			args:            []string{"check", "--version-base=1.0.0", "--version-target=2.0.0"},
+			expectedCommand: CommandCheck,
+		},
+		{
+			name:          "Check without target version",
+			args:          []string{"check", "--version-base=1.0.0"},
+			expectedError: "invalid configuration: version-target is required (use -t or --version-target)",
+		},
+		{
+			name:          "Upgrade without target version",
+			args:          []string{"upgrade", "--version-base=1.0.0"},
+			expectedError: "invalid configuration: version-target is required (use -t or --version-target)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = append(append([]string{"cmd"}, tt.args...),
+				"--values=test.yaml",
+				"--in-place",
+				"--repository=https://charts.example.com",
+				"--chart=mychart",
+			)
+
+			cfg, err := Parse()
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("Expected error '%s', got '%v'", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.Command != tt.expectedCommand {
+				t.Errorf("Expected command '%s', got '%s'", tt.expectedCommand, cfg.Command)
+			}
+		})
+	}
+}
+
+func TestParse_CheckWithoutOutput(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"check",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"--values=test.yaml",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+	}
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Command != CommandCheck {
+		t.Errorf("Expected command '%s', got '%s'", CommandCheck, cfg.Command)
+	}
+}
+
+func TestParse_VerifyRender(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"--values=test.yaml",
+		"--in-place",
+		"--repository=https://charts.example.com",
+		"--chart=mychart",
+		"--verify-render",
+		"--kube-version=1.29.0",
+		"--api-versions=monitoring.coreos.com/v1",
+		"--api-versions=cert-manager.io/v1",
+		"--allowed-kinds=Deployment,ConfigMap",
+	}
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !cfg.VerifyRender {
+		t.Errorf("Expected verify-render to be true, got false")
+	}
+	if cfg.KubeVersion != "1.29.0" {
+		t.Errorf("Expected kube-version '1.29.0', got '%s'", cfg.KubeVersion)
+	}
+	if len(cfg.APIVersions) != 2 || cfg.APIVersions[0] != "monitoring.coreos.com/v1" || cfg.APIVersions[1] != "cert-manager.io/v1" {
+		t.Errorf("Expected two api-versions, got %v", cfg.APIVersions)
+	}
+	if len(cfg.AllowedKinds) != 2 || cfg.AllowedKinds[0] != "Deployment" || cfg.AllowedKinds[1] != "ConfigMap" {
+		t.Errorf("Expected allowed-kinds Deployment and ConfigMap, got %v", cfg.AllowedKinds)
+	}
+}
+
+func TestParse_Report(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedFormat string
+		expectError    bool
+	}{
+		{
+			name:           "format from flag",
+			args:           []string{"--report-format=YAML"},
+			expectedFormat: "YAML",
+		},
+		{
+			name:           "format from yaml file extension",
+			args:           []string{"--report-file=report.yml"},
+			expectedFormat: "yaml",
+		},
+		{
+			name:           "format from markdown file extension",
+			args:           []string{"--report-file=summary.md"},
+			expectedFormat: "markdown",
+		},
+		{
+			name:           "format from other file extension",
+			args:           []string{"--report-file=report.out"},
+			expectedFormat: "json",
+		},
+		{
+			name:           "explicit format wins over extension",
+			args:           []string{"--report-file=report.yaml", "--report-format=json"},
+			expectedFormat: "json",
+		},
+		{
+			name:        "unknown format",
+			args:        []string{"--report-format=xml"},
+			expectError: true,
+		},
+		{
+			name:        "report on stdout with dry-run",
+			args:        []string{"--report-format=json", "--dry-run=diff"},
+			expectError: true,
+		},
+		{
+			name:           "report file with dry-run",
+			args:           []string{"--report-file=report.json", "--dry-run"},
+			expectedFormat: "json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = append([]string{
+				"cmd",
+				"--version-base=1.0.0",
+				"--version-target=2.0.0",
+				"--values=test.yaml",
+				"--in-place",
+				"--repository=https://charts.example.com",
+				"--chart=mychart",
+			}, tt.args...)
+
+			cfg, err := Parse()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if cfg.ReportFormat != tt.expectedFormat {
+				t.Errorf("Expected report-format '%s', got '%s'", tt.expectedFormat, cfg.ReportFormat)
+			}
+		})
+	}
+}
+
+func TestParse_DryRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedMode    string
+		expectedContext int
+		expectError     bool
+	}{
+		{
+			name:            "disabled",
+			args:            []string{},
+			expectedMode:    "",
+			expectedContext: 3,
+		},
+		{
+			name:            "bare flag prints full file",
+			args:            []string{"--dry-run"},
+			expectedMode:    DryRunFull,
+			expectedContext: 3,
+		},
+		{
+			name:            "short flag",
+			args:            []string{"-d"},
+			expectedMode:    DryRunFull,
+			expectedContext: 3,
+		},
+		{
+			name:            "diff with context",
+			args:            []string{"--dry-run=diff", "--diff-context=1"},
+			expectedMode:    DryRunDiff,
+			expectedContext: 1,
+		},
+		{
+			name:        "unknown mode",
+			args:        []string{"--dry-run=patch"},
+			expectError: true,
+		},
+		{
+			name:        "negative context",
+			args:        []string{"--dry-run=diff", "--diff-context=-1"},
+			expectError: true,
+		},
+		{
+			name:        "mode separated by a space",
+			args:        []string{"--dry-run", "diff", "--diff-context=1"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = append([]string{
+				"cmd",
+				"--version-base=1.0.0",
+				"--version-target=2.0.0",
+				"--values=test.yaml",
+				"--in-place",
+				"--repository=https://charts.example.com",
+				"--chart=mychart",
+			}, tt.args...)
+
+			cfg, err := Parse()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if cfg.DryRun != tt.expectedMode {
+				t.Errorf("Expected dry-run '%s', got '%s'", tt.expectedMode, cfg.DryRun)
+			}
+			if cfg.DiffContext != tt.expectedContext {
+				t.Errorf("Expected diff-context %d, got %d", tt.expectedContext, cfg.DiffContext)
+			}
+		})
+	}
+}
+
+func TestParse_Explain(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectedPath  string
+		expectedError string
+	}{
+		{
+			name:         "Path before flags",
+			args:         []string{"explain", "alertmanager.config.route", "--version-base=1.0.0", "--version-target=2.0.0"},
+			expectedPath: "alertmanager.config.route",
+		},
+		{
+			name:         "Path after flags",
+			args:         []string{"explain", "--version-base=1.0.0", "--version-target=2.0.0", `podAnnotations."prometheus.io/scrape"`},
+			expectedPath: `podAnnotations."prometheus.io/scrape"`,
+		},
+		{
+			name:          "Missing path",
+			args:          []string{"explain", "--version-base=1.0.0", "--version-target=2.0.0"},
+			expectedError: "invalid configuration: explain requires a path, e.g. helm valgrade explain image.tag",
+		},
+		{
+			name:          "Extra argument after the path",
+			args:          []string{"explain", "image.tag", "--version-base=1.0.0", "replicas", "--version-target=2.0.0"},
+			expectedError: `invalid configuration: unexpected argument "replicas", flags after it were not parsed (use --dry-run=diff instead of --dry-run diff)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = []string{"cmd", tt.args[0], tt.args[1]}
+			os.Args = append(append(os.Args,
+				"--values=test.yaml",
+				"--repository=https://charts.example.com",
+				"--chart=mychart",
+			), tt.args[2:]...)
+
+			cfg, err := Parse()
+			if tt.expectedError != "" {
+				if err == nil || err.Error() != tt.expectedError {
+					t.Fatalf("Expected error '%s', got '%v'", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.Command != CommandExplain {
+				t.Errorf("Expected command '%s', got '%s'", CommandExplain, cfg.Command)
+			}
+			if cfg.ExplainPath != tt.expectedPath {
+				t.Errorf("Expected path '%s', got '%s'", tt.expectedPath, cfg.ExplainPath)
+			}
+		})
+	}
+}
+
+func TestParse_UnknownFlag(t *testing.T) {
+	os.Args = []string{
+		"cmd",
+		"check",
+		"--version-base=1.0.0",
+		"--version-target=2.0.0",
+		"--values=test.yaml",
+		"--bogus",
+	}
+
+	_, err := Parse()
+	if err == nil || err.Error() != "flag provided but not defined: -bogus" {
+		t.Errorf("Parse() error = %v, want an unknown flag error", err)
+	}
+}
@@ -0,0 +1,341 @@
+package crd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/schema"
+)
+
+const kind = "CustomResourceDefinition"
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+type Change struct {
+	Name    string
+	File    string
+	Type    ChangeType
+	Details []string
+}
+
+type Definition struct {
+	Name     string
+	File     string
+	Scope    string
+	Versions []Version
+
+	raw map[string]interface{}
+}
+
+type Version struct {
+	Name    string
+	Served  bool
+	Storage bool
+	Schema  *schema.Schema
+}
+
+type manifest struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Scope    string `yaml:"scope"`
+		Version  string `yaml:"version"`
+		Versions []struct {
+			Name    string `yaml:"name"`
+			Served  bool   `yaml:"served"`
+			Storage bool   `yaml:"storage"`
+			Schema  struct {
+				OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+		Validation struct {
+			OpenAPIV3Schema map[string]interface{} `yaml:"openAPIV3Schema"`
+		} `yaml:"validation"`
+	} `yaml:"spec"`
+}
+
+func Load(c *chart.Chart) (map[string]*Definition, error) {
+	definitions := make(map[string]*Definition)
+
+	for _, obj := range c.CRDObjects() {
+		decoder := yaml.NewDecoder(bytes.NewReader(obj.File.Data))
+		for {
+			var node yaml.Node
+			if err := decoder.Decode(&node); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("failed to parse CRD file %s: %w", obj.Filename, err)
+			}
+
+			definition, err := parse(obj.Filename, &node)
+			if err != nil {
+				return nil, err
+			}
+			if definition != nil {
+				definitions[definition.Name] = definition
+			}
+		}
+	}
+
+	return definitions, nil
+}
+
+func parse(filename string, node *yaml.Node) (*Definition, error) {
+	var m manifest
+	if err := node.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse CRD file %s: %w", filename, err)
+	}
+	if m.Kind != kind || m.Metadata.Name == "" {
+		return nil, nil
+	}
+
+	var raw map[string]interface{}
+	if err := node.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse CRD file %s: %w", filename, err)
+	}
+
+	definition := &Definition{
+		Name:  m.Metadata.Name,
+		File:  filename,
+		Scope: m.Spec.Scope,
+		raw:   raw,
+	}
+
+	shared, err := parseSchema(m.Spec.Validation.OpenAPIV3Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the schema of CRD %s: %w", m.Metadata.Name, err)
+	}
+
+	if len(m.Spec.Versions) == 0 && m.Spec.Version != "" {
+		definition.Versions = append(definition.Versions, Version{Name: m.Spec.Version, Served: true, Storage: true, Schema: shared})
+	}
+	for _, v := range m.Spec.Versions {
+		s := shared
+		if v.Schema.OpenAPIV3Schema != nil {
+			s, err = parseSchema(v.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the schema of CRD %s version %s: %w", m.Metadata.Name, v.Name, err)
+			}
+		}
+		definition.Versions = append(definition.Versions, Version{Name: v.Name, Served: v.Served, Storage: v.Storage, Schema: s})
+	}
+
+	return definition, nil
+}
+
+func parseSchema(openAPIV3Schema map[string]interface{}) (*schema.Schema, error) {
+	if openAPIV3Schema == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(openAPIV3Schema)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Parse(data)
+}
+
+func Compare(base, target *chart.Chart) ([]Change, error) {
+	baseDefinitions, err := Load(base)
+	if err != nil {
+		return nil, err
+	}
+	targetDefinitions, err := Load(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for name, b := range baseDefinitions {
+		if _, ok := targetDefinitions[name]; !ok {
+			changes = append(changes, Change{Name: name, File: b.File, Type: ChangeRemoved})
+		}
+	}
+	for name, t := range targetDefinitions {
+		b, ok := baseDefinitions[name]
+		if !ok {
+			changes = append(changes, Change{Name: name, File: t.File, Type: ChangeAdded})
+			continue
+		}
+		if reflect.DeepEqual(b.raw, t.raw) {
+			continue
+		}
+
+		details := compareDefinitions(b, t)
+		if len(details) == 0 {
+			details = []string{"definition changed outside of its versions and schemas"}
+		}
+		changes = append(changes, Change{Name: name, File: t.File, Type: ChangeModified, Details: details})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+func compareDefinitions(base, target *Definition) []string {
+	var details []string
+
+	if base.Scope != target.Scope {
+		details = append(details, fmt.Sprintf("scope changed from %s to %s", base.Scope, target.Scope))
+	}
+
+	baseVersions := make(map[string]Version, len(base.Versions))
+	for _, v := range base.Versions {
+		baseVersions[v.Name] = v
+		if v.Storage && !storage(target, v.Name) {
+			if s := storageName(target); s != "" {
+				details = append(details, fmt.Sprintf("storage version changed from %s to %s", v.Name, s))
+			}
+		}
+	}
+
+	targetVersions := make(map[string]bool, len(target.Versions))
+	for _, v := range target.Versions {
+		targetVersions[v.Name] = true
+
+		b, ok := baseVersions[v.Name]
+		if !ok {
+			details = append(details, fmt.Sprintf("version %s added", v.Name))
+			continue
+		}
+		if b.Served && !v.Served {
+			details = append(details, fmt.Sprintf("version %s is no longer served", v.Name))
+		}
+		if !b.Served && v.Served {
+			details = append(details, fmt.Sprintf("version %s is now served", v.Name))
+		}
+		for _, field := range compareFields(fields(b.Schema), fields(v.Schema)) {
+			details = append(details, fmt.Sprintf("version %s: %s", v.Name, field))
+		}
+	}
+
+	for _, v := range base.Versions {
+		if !targetVersions[v.Name] {
+			details = append(details, fmt.Sprintf("version %s removed", v.Name))
+		}
+	}
+
+	return details
+}
+
+func storage(d *Definition, name string) bool {
+	for _, v := range d.Versions {
+		if v.Name == name {
+			return v.Storage
+		}
+	}
+	return false
+}
+
+func storageName(d *Definition) string {
+	for _, v := range d.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+type field struct {
+	Type     string
+	Required bool
+}
+
+func fields(s *schema.Schema) map[string]field {
+	result := make(map[string]field)
+	collectFields(result, "", s, 0)
+	return result
+}
+
+func collectFields(result map[string]field, prefix string, s *schema.Schema, depth int) {
+	if s == nil || depth > 64 {
+		return
+	}
+
+	for name, child := range s.Properties {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		result[path] = field{
+			Type:     strings.Join(child.Type, ","),
+			Required: contains(s.Required, name),
+		}
+		collectFields(result, path, child, depth+1)
+	}
+
+	if items := s.ItemSchema(); items != nil {
+		collectFields(result, prefix+"[]", items, depth+1)
+	}
+}
+
+func compareFields(base, target map[string]field) []string {
+	paths := make([]string, 0, len(base)+len(target))
+	for path := range base {
+		paths = append(paths, path)
+	}
+	for path := range target {
+		if _, ok := base[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var details []string
+	for _, path := range paths {
+		b, inBase := base[path]
+		t, inTarget := target[path]
+		switch {
+		case !inTarget:
+			details = append(details, fmt.Sprintf("field %s removed", path))
+		case !inBase && t.Required:
+			details = append(details, fmt.Sprintf("required field %s added", path))
+		case !inBase:
+			details = append(details, fmt.Sprintf("field %s added", path))
+		default:
+			if b.Type != t.Type {
+				details = append(details, fmt.Sprintf("field %s type changed from %s to %s", path, typeName(b.Type), typeName(t.Type)))
+			}
+			if !b.Required && t.Required {
+				details = append(details, fmt.Sprintf("field %s is now required", path))
+			}
+		}
+	}
+
+	return details
+}
+
+func typeName(t string) string {
+	if t == "" {
+		return "any"
+	}
+	return t
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package crd
+
+import (
+	"reflect"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+)
+
+func testChart(crds map[string]string) *chart.Chart {
+	c := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "operator"}}
+	for name, data := range crds {
+		c.Files = append(c.Files, &helmchart.File{Name: "crds/" + name, Data: []byte(data)})
+	}
+	return &chart.Chart{Chart: c}
+}
+
+const widgetsBase = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  versions:
+    - name: v1alpha1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              properties:
+                size:
+                  type: string
+                color:
+                  type: string
+                ports:
+                  type: array
+                  items:
+                    type: object
+                    properties:
+                      port:
+                        type: integer
+`
+
+const widgetsTarget = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  versions:
+    - name: v1alpha1
+      served: false
+      storage: false
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              required: [owner]
+              properties:
+                size:
+                  type: integer
+                owner:
+                  type: string
+                ports:
+                  type: array
+                  items:
+                    type: object
+                    required: [port]
+                    properties:
+                      port:
+                        type: integer
+                      protocol:
+                        type: string
+    - name: v1
+      served: true
+      storage: true
+`
+
+const gadgets = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  scope: Cluster
+  versions:
+    - name: v1
+      served: true
+      storage: true
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+`
+
+const gizmosBase = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gizmos.example.com
+  annotations:
+    controller-gen.kubebuilder.io/version: v0.14.0
+spec:
+  group: example.com
+  scope: Namespaced
+  versions:
+    - name: v1
+      served: true
+      storage: true
+`
+
+const gizmosTarget = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gizmos.example.com
+  annotations:
+    controller-gen.kubebuilder.io/version: v0.15.0
+spec:
+  group: example.com
+  scope: Namespaced
+  versions:
+    - name: v1
+      served: true
+      storage: true
+`
+
+const doohickeys = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: doohickeys.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  versions:
+    - name: v1
+      served: true
+      storage: true
+`
+
+func TestCompare(t *testing.T) {
+	base := testChart(map[string]string{
+		"widgets.yaml": widgetsBase,
+		"gadgets.yaml": gadgets,
+		"gizmos.yaml":  gizmosBase,
+	})
+	target := testChart(map[string]string{
+		"widgets.yaml":    widgetsTarget,
+		"gizmos.yaml":     gizmosTarget,
+		"doohickeys.yaml": doohickeys,
+	})
+
+	changes, err := Compare(base, target)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	expected := []Change{
+		{Name: "doohickeys.example.com", File: "operator/crds/doohickeys.yaml", Type: ChangeAdded},
+		{Name: "gadgets.example.com", File: "operator/crds/gadgets.yaml", Type: ChangeRemoved},
+		{
+			Name:    "gizmos.example.com",
+			File:    "operator/crds/gizmos.yaml",
+			Type:    ChangeModified,
+			Details: []string{"definition changed outside of its versions and schemas"},
+		},
+		{
+			Name: "widgets.example.com",
+			File: "operator/crds/widgets.yaml",
+			Type: ChangeModified,
+			Details: []string{
+				"storage version changed from v1alpha1 to v1",
+				"version v1alpha1 is no longer served",
+				"version v1alpha1: field spec.color removed",
+				"version v1alpha1: required field spec.owner added",
+				"version v1alpha1: field spec.ports[].port is now required",
+				"version v1alpha1: field spec.ports[].protocol added",
+				"version v1alpha1: field spec.size type changed from string to integer",
+				"version v1 added",
+			},
+		},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Compare() = %#v, want %#v", changes, expected)
+	}
+}
+
+func TestCompareInvalidFile(t *testing.T) {
+	base := testChart(map[string]string{"broken.yaml": "kind: [CustomResourceDefinition"})
+	if _, err := Compare(base, testChart(nil)); err == nil {
+		t.Error("Compare() expected an error for an invalid CRD file")
+	}
+}
@@ -2,145 +2,597 @@ package diff
 
 import (
 	"fmt"
-	"reflect"
+	"sort"
 
 	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/schema"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+	"gopkg.in/yaml.v3"
 )
 
 type Result struct {
-	Added    map[string]interface{}
-	Removed  map[string]interface{}
-	Modified map[string]interface{}
+	Added     map[string]*yaml.Node
+	Removed   map[string]*yaml.Node
+	Modified  map[string]*yaml.Node
+	Defaults  *Result
+	Orphans   []Orphan
+	Warnings  []Warning
+	Decisions []Decision
 }
 
-func Compare(base, target *chart.Chart, userValues map[string]interface{}, keepValues []string, ignoreMissing bool) (*Result, error) {
-	result := &Result{
-		Added:    make(map[string]interface{}),
-		Removed:  make(map[string]interface{}),
-		Modified: make(map[string]interface{}),
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+type Action string
+
+const (
+	ActionKeep   Action = "keep"
+	ActionSet    Action = "set"
+	ActionDelete Action = "delete"
+	ActionIgnore Action = "ignore"
+	ActionSkip   Action = "skip"
+)
+
+type Decision struct {
+	Path            string
+	Change          ChangeType
+	Action          Action
+	Reason          string
+	Base            interface{}
+	Target          interface{}
+	User            interface{}
+	UserSet         bool
+	Conflict        bool
+	Position        values.Position
+	DefaultPosition values.Position
+}
+
+type Warning struct {
+	Path    string
+	Message string
+}
+
+type Options struct {
+	KeepValues           []string
+	IgnoreMissing        bool
+	PinDefaults          bool
+	PinUserValues        bool
+	Pins                 []values.Path
+	CaseInsensitiveBools bool
+	EmbeddedPaths        []string
+}
+
+type comparison struct {
+	opts          Options
+	keepPaths     []values.Path
+	embeddedPaths []values.Path
+	base          *values.Tree
+	target        *values.Tree
+	user          *values.Tree
+	userChanges   map[string]*yaml.Node
+	decoded       map[*yaml.Node]interface{}
+	result        *Result
+}
+
+type nodes struct {
+	base   *yaml.Node
+	target *yaml.Node
+	user   *yaml.Node
+}
+
+func Compare(base, target *chart.Chart, userValues map[string]interface{}, opts Options) (*Result, error) {
+	user, err := values.TreeFromMap(userValues, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareTree(base, target, user, opts)
+}
+
+func CompareTree(base, target *chart.Chart, user *values.Tree, opts Options) (*Result, error) {
+	c, err := newComparison(base, target, user, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	baseValues := base.GetDefaultValues()
-	targetValues := target.GetDefaultValues()
+	return c.compare(base, target)
+}
 
-	userChanges := identifyUserChanges("", baseValues, userValues)
+func newComparison(base, target *chart.Chart, user *values.Tree, opts Options) (*comparison, error) {
+	keepPaths, err := values.ParsePaths(opts.KeepValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keep values: %w", err)
+	}
+
+	embeddedPaths, err := values.ParsePaths(opts.EmbeddedPaths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded paths: %w", err)
+	}
 
-	err := compareValues("", baseValues, targetValues, userChanges, keepValues, ignoreMissing, result)
+	baseTree, err := base.GetDefaultValuesTree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base values: %w", err)
+	}
+
+	targetTree, err := target.GetDefaultValuesTree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target values: %w", err)
+	}
+
+	result := newResult()
+	if !opts.PinDefaults {
+		result.Defaults = newResult()
+	}
+
+	c := &comparison{
+		opts:          opts,
+		keepPaths:     keepPaths,
+		embeddedPaths: embeddedPaths,
+		base:          baseTree,
+		target:        targetTree,
+		user:          user,
+		decoded:       make(map[*yaml.Node]interface{}),
+		result:        result,
+	}
+	c.userChanges, err = c.identifyUserChanges(nil, baseTree.Root, user.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare user values: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *comparison) compare(base, target *chart.Chart) (*Result, error) {
+	result := c.result
+
+	err := c.compareValues(nil, c.base.Root, c.target.Root, c.user.Root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compare values: %w", err)
 	}
 
+	var freeForm []values.Path
+	for _, ch := range []*chart.Chart{base, target} {
+		s, err := schema.Parse(ch.GetSchema())
+		if err != nil {
+			result.Warnings = append(result.Warnings, Warning{
+				Message: fmt.Sprintf("values.schema.json of %s %s is invalid and is ignored when looking for unknown keys: %v", ch.GetName(), ch.GetVersion(), err),
+			})
+			continue
+		}
+		freeForm = append(freeForm, s.FreeForm()...)
+	}
+	baseDefaults, err := base.GetAllDefaultValuesTree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base values: %w", err)
+	}
+	targetDefaults, err := target.GetAllDefaultValuesTree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target values: %w", err)
+	}
+	result.Orphans = findOrphans(baseDefaults.Root, targetDefaults.Root, c.user, freeForm, c.keepPaths)
+
+	sortDecisions(result.Decisions)
+	sort.SliceStable(result.Warnings, func(i, j int) bool {
+		return result.Warnings[i].Path < result.Warnings[j].Path
+	})
+
 	cleanupEmptyMaps(result)
+	if result.Defaults != nil {
+		cleanupEmptyMaps(result.Defaults)
+		if result.Defaults.empty() {
+			result.Defaults = nil
+		}
+	}
 
 	return result, nil
 }
 
-func identifyUserChanges(prefix string, base, user map[string]interface{}) map[string]interface{} {
-	changes := make(map[string]interface{})
-
-	for k, v := range user {
-		path := joinPath(prefix, k)
-		baseVal, baseExists := base[k]
+func SortedKeys(m map[string]*yaml.Node) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortKeys(keys)
+	return keys
+}
 
-		if !baseExists {
-			changes[path] = v
-			continue
+func sortKeys(keys []string) {
+	paths := make(map[string]values.Path, len(keys))
+	for _, k := range keys {
+		path, err := values.ParsePath(k)
+		if err != nil {
+			path = values.KeyPath(k)
 		}
+		paths[k] = path
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if c := paths[keys[i]].Compare(paths[keys[j]]); c != 0 {
+			return c < 0
+		}
+		return keys[i] < keys[j]
+	})
+}
 
-		if reflect.TypeOf(v) != reflect.TypeOf(baseVal) {
-			changes[path] = v
+func sortDecisions(decisions []Decision) {
+	keys := make([]string, len(decisions))
+	for i, d := range decisions {
+		keys[i] = d.Path
+	}
+	sortKeys(keys)
+
+	order := make(map[string]int, len(keys))
+	for i, k := range keys {
+		order[k] = i
+	}
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return order[decisions[i].Path] < order[decisions[j].Path]
+	})
+}
+
+func newResult() *Result {
+	return &Result{
+		Added:    make(map[string]*yaml.Node),
+		Removed:  make(map[string]*yaml.Node),
+		Modified: make(map[string]*yaml.Node),
+	}
+}
+
+func (r *Result) empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Modified) == 0
+}
+
+func (c *comparison) identifyUserChanges(prefix values.Path, base, user *yaml.Node) (map[string]*yaml.Node, error) {
+	changes := make(map[string]*yaml.Node)
+
+	for i := 0; i+1 < len(user.Content); i += 2 {
+		k, v := user.Content[i].Value, user.Content[i+1]
+		path := prefix.Child(k)
+		baseVal := mapValue(base, k)
+
+		if baseVal == nil {
+			changes[path.String()] = v
 			continue
 		}
 
-		switch typedV := v.(type) {
-		case map[string]interface{}:
-			subChanges := identifyUserChanges(path, baseVal.(map[string]interface{}), typedV)
+		if isMap(v) && isMap(baseVal) {
+			subChanges, err := c.identifyUserChanges(path, baseVal, v)
+			if err != nil {
+				return nil, err
+			}
 			for subK, subV := range subChanges {
 				changes[subK] = subV
 			}
-		default:
-			if !reflect.DeepEqual(v, baseVal) {
-				changes[path] = v
-			}
+			continue
+		}
+
+		if isMap(v) || isMap(baseVal) || c.isPinned(path) {
+			changes[path.String()] = v
+			continue
+		}
+		equal, err := c.equal(path, v, baseVal)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			changes[path.String()] = v
 		}
 	}
 
-	return changes
+	return changes, nil
+}
+
+func (c *comparison) isPinned(path values.Path) bool {
+	if c.opts.PinUserValues {
+		return true
+	}
+	for _, pin := range c.opts.Pins {
+		if path.HasPrefix(pin) || pin.HasPrefix(path) {
+			return true
+		}
+	}
+	return false
 }
 
-func compareValues(prefix string, base, target, userChanges map[string]interface{}, keepValues []string, ignoreMissing bool, result *Result) error {
-	for k, v := range target {
-		path := joinPath(prefix, k)
+func (c *comparison) compareValues(prefix values.Path, base, target, user *yaml.Node) error {
+	for i := 0; i+1 < len(target.Content); i += 2 {
+		k := target.Content[i].Value
+		path := prefix.Child(k)
+		key := path.String()
 
-		if shouldKeep(path, keepValues) {
+		n := nodes{base: mapValue(base, k), target: target.Content[i+1], user: mapValue(user, k)}
+		baseExists := n.base != nil
+		_, userChanged := c.userChanges[key]
+
+		if shouldKeep(path, c.keepPaths) {
+			equal, err := c.equal(path, n.base, n.target)
+			if err != nil {
+				return err
+			}
+			if !baseExists || !equal {
+				if err := c.decide(path, n, changeType(baseExists), ActionSkip, "excluded with --keep"); err != nil {
+					return err
+				}
+			}
 			continue
 		}
 
-		baseVal, baseExists := base[k]
-		userVal, userChanged := userChanges[path]
+		if userChanged && isNull(n.user) {
+			if err := c.compareNulled(path, n); err != nil {
+				return err
+			}
+			continue
+		}
 
 		if !baseExists {
+			var err error
 			if userChanged {
-				result.Added[path] = userVal
+				c.result.Added[key] = n.user
+				err = c.decide(path, n, ChangeAdded, ActionKeep, "key added in the target version is already set by user values")
 			} else {
-				result.Added[path] = v
+				c.defaults().Added[key] = n.target
+				err = c.decideDefault(path, n, ChangeAdded)
+			}
+			if err != nil {
+				return err
 			}
 			continue
 		}
 
-		if reflect.TypeOf(v) != reflect.TypeOf(baseVal) {
-			if userChanged {
-				result.Modified[path] = userVal
-			} else {
-				result.Modified[path] = v
+		if isMap(n.target) && isMap(n.base) {
+			if err := c.compareValues(path, n.base, n.target, n.user); err != nil {
+				return err
 			}
 			continue
 		}
 
-		switch typedV := v.(type) {
-		case map[string]interface{}:
-			err := compareValues(path, baseVal.(map[string]interface{}), typedV, userChanges, keepValues, ignoreMissing, result)
+		baseVal, err := c.decode(path, n.base)
+		if err != nil {
+			return err
+		}
+		targetVal, err := c.decode(path, n.target)
+		if err != nil {
+			return err
+		}
+		if Equal(baseVal, targetVal, c.opts) {
+			continue
+		}
+
+		reason := "user value is kept although the default changed"
+		if kindOf(targetVal) != kindOf(baseVal) {
+			reason = "user value is kept although the default changed its type"
+		} else if userChanged {
+			merged, err := c.mergeEmbedded(path, n, baseVal, targetVal)
 			if err != nil {
 				return err
 			}
-		default:
-			if !reflect.DeepEqual(v, baseVal) {
-				if userChanged {
-					result.Modified[path] = userVal
-				} else {
-					result.Modified[path] = v
-				}
+			if merged {
+				continue
 			}
 		}
+
+		switch {
+		case userChanged:
+			c.result.Modified[key] = n.user
+			err = c.decide(path, n, ChangeModified, ActionKeep, reason)
+		case n.user != nil:
+			c.result.Modified[key] = n.target
+			err = c.decide(path, n, ChangeModified, ActionSet, "user value equals the default of the base version and is updated to the new default")
+		default:
+			c.defaults().Modified[key] = n.target
+			err = c.decideDefault(path, n, ChangeModified)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
-	if !ignoreMissing {
-		for k, v := range base {
-			path := joinPath(prefix, k)
+	if !c.opts.IgnoreMissing {
+		for i := 0; i+1 < len(base.Content); i += 2 {
+			k := base.Content[i].Value
+			path := prefix.Child(k)
 
-			if shouldKeep(path, keepValues) {
+			if mapValue(target, k) != nil {
 				continue
 			}
 
-			if _, exists := target[k]; !exists {
-				result.Removed[path] = v
+			n := nodes{base: base.Content[i+1], user: mapValue(user, k)}
+			var err error
+			switch {
+			case shouldKeep(path, c.keepPaths):
+				err = c.decide(path, n, ChangeRemoved, ActionSkip, "excluded with --keep")
+			case n.user != nil:
+				c.result.Removed[path.String()] = n.base
+				err = c.decide(path, n, ChangeRemoved, ActionDelete, "key removed in the target version is deleted from the user values")
+			default:
+				c.defaults().Removed[path.String()] = n.base
+				err = c.decideDefault(path, n, ChangeRemoved)
 			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *comparison) compareNulled(path values.Path, n nodes) error {
+	if n.base == nil {
+		return c.decide(path, n, ChangeAdded, ActionSkip, "value is nulled out by user values")
+	}
+
+	baseVal, err := c.decode(path, n.base)
+	if err != nil {
+		return err
+	}
+	targetVal, err := c.decode(path, n.target)
+	if err != nil {
+		return err
+	}
+
+	if restructured(baseVal, targetVal) {
+		c.result.Warnings = append(c.result.Warnings, Warning{
+			Path:    path.String(),
+			Message: "value is nulled out by user values but its defaults were restructured in the target version",
+		})
+	}
+	if Equal(baseVal, targetVal, c.opts) {
+		return nil
+	}
+	return c.decide(path, n, ChangeModified, ActionSkip, "value is nulled out by user values")
+}
+
+func (c *comparison) decide(path values.Path, n nodes, change ChangeType, action Action, reason string) error {
+	d, err := c.decision(path, n, change, action, reason)
+	if err != nil {
+		return err
+	}
+	c.result.Decisions = append(c.result.Decisions, d)
+	return nil
+}
+
+func (c *comparison) decision(path values.Path, n nodes, change ChangeType, action Action, reason string) (Decision, error) {
+	base, err := c.decode(path, n.base)
+	if err != nil {
+		return Decision{}, err
+	}
+	target, err := c.decode(path, n.target)
+	if err != nil {
+		return Decision{}, err
+	}
+	user, err := c.decode(path, n.user)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	defaultPosition := c.target.Position(n.target)
+	if n.target == nil {
+		defaultPosition = c.base.Position(n.base)
+	}
+
+	return Decision{
+		Path:            path.String(),
+		Change:          change,
+		Action:          action,
+		Reason:          reason,
+		Base:            base,
+		Target:          target,
+		User:            user,
+		UserSet:         n.user != nil,
+		Conflict:        action == ActionKeep && n.user != nil && !Equal(user, target, c.opts),
+		Position:        c.user.Position(n.user),
+		DefaultPosition: defaultPosition,
+	}, nil
+}
+
+func (c *comparison) decideDefault(path values.Path, n nodes, change ChangeType) error {
+	if c.result.Defaults == nil {
+		action := ActionSet
+		if change == ChangeRemoved {
+			action = ActionDelete
 		}
+		return c.decide(path, n, change, action, "chart default is pinned into the values file with --pin-defaults")
+	}
+	return c.decide(path, n, change, ActionIgnore, "chart default changed and is not overridden by user values")
+}
+
+func (c *comparison) equal(path values.Path, a, b *yaml.Node) (bool, error) {
+	aVal, err := c.decode(path, a)
+	if err != nil {
+		return false, err
+	}
+	bVal, err := c.decode(path, b)
+	if err != nil {
+		return false, err
+	}
+	return Equal(aVal, bVal, c.opts), nil
+}
+
+func (c *comparison) decode(path values.Path, node *yaml.Node) (interface{}, error) {
+	if node == nil {
+		return nil, nil
+	}
+	if v, ok := c.decoded[node]; ok {
+		return v, nil
+	}
+
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	c.decoded[node] = v
+	return v, nil
+}
+
+func changeType(baseExists bool) ChangeType {
+	if baseExists {
+		return ChangeModified
 	}
+	return ChangeAdded
+}
 
+func mapValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
 	return nil
 }
 
-func joinPath(prefix, key string) string {
-	if prefix == "" {
-		return key
+func isMap(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+func (c *comparison) defaults() *Result {
+	if c.result.Defaults != nil {
+		return c.result.Defaults
+	}
+	return c.result
+}
+
+func restructured(base, target interface{}) bool {
+	if kindOf(base) != kindOf(target) {
+		return true
 	}
-	return prefix + "." + key
+
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	targetMap := target.(map[string]interface{})
+
+	if len(baseMap) != len(targetMap) {
+		return true
+	}
+	for k, baseVal := range baseMap {
+		targetVal, exists := targetMap[k]
+		if !exists || restructured(baseVal, targetVal) {
+			return true
+		}
+	}
+	return false
+}
+
+func shouldKeep(path values.Path, keepValues []values.Path) bool {
+	return hasAnyPrefix(path, keepValues)
 }
 
-func shouldKeep(path string, keepValues []string) bool {
-	for _, keep := range keepValues {
-		if path == keep || (len(path) > len(keep) && path[:len(keep)] == keep && path[len(keep)] == '.') {
+func hasAnyPrefix(path values.Path, prefixes []values.Path) bool {
+	for _, prefix := range prefixes {
+		if path.HasPrefix(prefix) {
 			return true
 		}
 	}
@@ -158,3 +610,63 @@ func cleanupEmptyMaps(result *Result) {
 		result.Modified = nil
 	}
 }
+
+type StaleOverride struct {
+	Path       values.Path
+	Candidates []values.Path
+}
+
+func StaleOverrides(result *Result, paths []values.Path) ([]StaleOverride, error) {
+	var removed []values.Path
+	for k := range result.Removed {
+		path, err := values.ParsePath(k)
+		if err != nil {
+			return nil, err
+		}
+		removed = append(removed, path)
+	}
+
+	var added []values.Path
+	for _, r := range []*Result{result, result.Defaults} {
+		if r == nil {
+			continue
+		}
+		for k, node := range r.Added {
+			path, err := values.ParsePath(k)
+			if err != nil {
+				return nil, err
+			}
+			added = append(added, flattenPaths(path, node)...)
+		}
+	}
+	values.SortPaths(added)
+
+	var stale []StaleOverride
+	for _, path := range paths {
+		if !hasAnyPrefix(path, removed) {
+			continue
+		}
+
+		override := StaleOverride{Path: path}
+		for _, candidate := range added {
+			if candidate.Last() == path.Last() {
+				override.Candidates = append(override.Candidates, candidate)
+			}
+		}
+		stale = append(stale, override)
+	}
+
+	return stale, nil
+}
+
+func flattenPaths(prefix values.Path, node *yaml.Node) []values.Path {
+	if !isMap(node) || len(node.Content) == 0 {
+		return []values.Path{prefix}
+	}
+
+	var paths []values.Path
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		paths = append(paths, flattenPaths(prefix.Child(node.Content[i].Value), node.Content[i+1])...)
+	}
+	return paths
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+	"gopkg.in/yaml.v3"
 	helmchart "helm.sh/helm/v3/pkg/chart"
 )
 
@@ -17,35 +19,35 @@ func TestCompare(t *testing.T) {
 		user          map[string]interface{}
 		keepValues    []string
 		ignoreMissing bool
-		expected      Result
+		expected      changes
 	}{
 		{
 			name:     "Simple addition",
 			base:     map[string]interface{}{"a": 1},
 			target:   map[string]interface{}{"a": 1, "b": 2},
 			user:     map[string]interface{}{},
-			expected: Result{Added: map[string]interface{}{"b": 2}},
+			expected: changes{Added: map[string]interface{}{"b": 2}},
 		},
 		{
 			name:     "Simple removal",
 			base:     map[string]interface{}{"a": 1, "b": 2},
 			target:   map[string]interface{}{"a": 1},
 			user:     map[string]interface{}{},
-			expected: Result{Removed: map[string]interface{}{"b": 2}},
+			expected: changes{Removed: map[string]interface{}{"b": 2}},
 		},
 		{
 			name:     "Simple modification",
 			base:     map[string]interface{}{"a": 1},
 			target:   map[string]interface{}{"a": 2},
 			user:     map[string]interface{}{},
-			expected: Result{Modified: map[string]interface{}{"a": 2}},
+			expected: changes{Modified: map[string]interface{}{"a": 2}},
 		},
 		{
 			name:   "Nested changes",
 			base:   map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
 			target: map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 3, "d": 4}},
 			user:   map[string]interface{}{},
-			expected: Result{
+			expected: changes{
 				Added:    map[string]interface{}{"a.d": 4},
 				Modified: map[string]interface{}{"a.c": 3},
 			},
@@ -56,7 +58,7 @@ func TestCompare(t *testing.T) {
 			target:     map[string]interface{}{"a": 2, "b": 3},
 			user:       map[string]interface{}{},
 			keepValues: []string{"a"},
-			expected:   Result{Modified: map[string]interface{}{"b": 3}},
+			expected:   changes{Modified: map[string]interface{}{"b": 3}},
 		},
 		{
 			name:          "Ignore missing",
@@ -64,21 +66,21 @@ func TestCompare(t *testing.T) {
 			target:        map[string]interface{}{"a": 1},
 			user:          map[string]interface{}{},
 			ignoreMissing: true,
-			expected:      Result{},
+			expected:      changes{},
 		},
 		{
 			name:     "User values",
 			base:     map[string]interface{}{"a": 1},
 			target:   map[string]interface{}{"a": 2},
 			user:     map[string]interface{}{"a": 3},
-			expected: Result{Modified: map[string]interface{}{"a": 3}},
+			expected: changes{Modified: map[string]interface{}{"a": 3}},
 		},
 		{
 			name:   "Complex nested changes with ignore missing",
 			base:   map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2, "d": 3}, "e": 4},
 			target: map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 3, "f": 5}, "g": 6},
 			user:   map[string]interface{}{"a": map[string]interface{}{"c": 4}},
-			expected: Result{
+			expected: changes{
 				Added:    map[string]interface{}{"a.f": 5, "g": 6},
 				Modified: map[string]interface{}{"a.c": 4},
 			},
@@ -90,7 +92,7 @@ func TestCompare(t *testing.T) {
 			target:     map[string]interface{}{"a": map[string]interface{}{"b": 2, "c": 3}, "d": 4},
 			user:       map[string]interface{}{},
 			keepValues: []string{"a.b"},
-			expected: Result{
+			expected: changes{
 				Modified: map[string]interface{}{"a.c": 3, "d": 4},
 			},
 		},
@@ -101,7 +103,11 @@ func TestCompare(t *testing.T) {
 			baseChart := createMockChart(tt.base)
 			targetChart := createMockChart(tt.target)
 
-			result, err := Compare(baseChart, targetChart, tt.user, tt.keepValues, tt.ignoreMissing)
+			result, err := Compare(baseChart, targetChart, tt.user, Options{
+				KeepValues:    tt.keepValues,
+				IgnoreMissing: tt.ignoreMissing,
+				PinDefaults:   true,
+			})
 			if err != nil {
 				t.Fatalf("Compare returned an error: %v", err)
 			}
@@ -114,6 +120,170 @@ func TestCompare(t *testing.T) {
 	}
 }
 
+func TestCompareOverridesOnly(t *testing.T) {
+	tests := []struct {
+		name             string
+		base             map[string]interface{}
+		target           map[string]interface{}
+		user             map[string]interface{}
+		expected         changes
+		expectedDefaults *changes
+	}{
+		{
+			name:             "Default changes are informational",
+			base:             map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			target:           map[string]interface{}{"a": 2, "b": 2, "d": 4},
+			user:             map[string]interface{}{},
+			expected:         changes{},
+			expectedDefaults: &changes{Added: map[string]interface{}{"d": 4}, Removed: map[string]interface{}{"c": 3}, Modified: map[string]interface{}{"a": 2}},
+		},
+		{
+			name:     "User overrides are kept",
+			base:     map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 1}},
+			target:   map[string]interface{}{"a": 2, "b": map[string]interface{}{"c": 1}},
+			user:     map[string]interface{}{"a": 3},
+			expected: changes{Modified: map[string]interface{}{"a": 3}},
+		},
+		{
+			name:     "User values equal to base defaults follow the target",
+			base:     map[string]interface{}{"a": 1, "b": 1},
+			target:   map[string]interface{}{"a": 2, "b": 1},
+			user:     map[string]interface{}{"a": 1},
+			expected: changes{Modified: map[string]interface{}{"a": 2}},
+		},
+		{
+			name:             "Removed keys present in user values are removed",
+			base:             map[string]interface{}{"a": 1, "b": 2},
+			target:           map[string]interface{}{},
+			user:             map[string]interface{}{"a": 1},
+			expected:         changes{Removed: map[string]interface{}{"a": 1}},
+			expectedDefaults: &changes{Removed: map[string]interface{}{"b": 2}},
+		},
+		{
+			name:     "No changes",
+			base:     map[string]interface{}{"a": 1},
+			target:   map[string]interface{}{"a": 1},
+			user:     map[string]interface{}{"a": 1},
+			expected: changes{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Compare(createMockChart(tt.base), createMockChart(tt.target), tt.user, Options{})
+			if err != nil {
+				t.Fatalf("Compare returned an error: %v", err)
+			}
+
+			if !resultEqual(*result, tt.expected) {
+				t.Errorf("Compare result mismatch.\n%s", detailedComparison(*result, tt.expected))
+			}
+
+			if (result.Defaults == nil) != (tt.expectedDefaults == nil) {
+				t.Fatalf("Defaults = %+v, want %+v", result.Defaults, tt.expectedDefaults)
+			}
+			if tt.expectedDefaults != nil && !resultEqual(*result.Defaults, *tt.expectedDefaults) {
+				t.Errorf("Defaults mismatch.\n%s", detailedComparison(*result.Defaults, *tt.expectedDefaults))
+			}
+		})
+	}
+}
+
+func TestComparePinnedValues(t *testing.T) {
+	base := map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "1.0", "pullPolicy": "IfNotPresent"}}
+	target := map[string]interface{}{"replicas": 2, "image": map[string]interface{}{"tag": "2.0", "pullPolicy": "Always"}}
+	user := map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "1.0", "pullPolicy": "IfNotPresent"}}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected changes
+	}{
+		{
+			name:     "Values equal to base defaults follow the target",
+			opts:     Options{PinDefaults: true},
+			expected: changes{Modified: map[string]interface{}{"replicas": 2, "image.tag": "2.0", "image.pullPolicy": "Always"}},
+		},
+		{
+			name:     "Pinned values are kept",
+			opts:     Options{PinDefaults: true, Pins: []values.Path{values.KeyPath("replicas"), values.KeyPath("image", "tag")}},
+			expected: changes{Modified: map[string]interface{}{"replicas": 1, "image.tag": "1.0", "image.pullPolicy": "Always"}},
+		},
+		{
+			name:     "Pinned maps keep all their values",
+			opts:     Options{PinDefaults: true, Pins: []values.Path{values.KeyPath("image")}},
+			expected: changes{Modified: map[string]interface{}{"replicas": 2, "image.tag": "1.0", "image.pullPolicy": "IfNotPresent"}},
+		},
+		{
+			name:     "Pinned values are kept without pin defaults",
+			opts:     Options{Pins: []values.Path{values.KeyPath("replicas")}},
+			expected: changes{Modified: map[string]interface{}{"replicas": 1, "image.tag": "2.0", "image.pullPolicy": "Always"}},
+		},
+		{
+			name:     "All user values pinned",
+			opts:     Options{PinUserValues: true},
+			expected: changes{Modified: map[string]interface{}{"replicas": 1, "image.tag": "1.0", "image.pullPolicy": "IfNotPresent"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Compare(createMockChart(base), createMockChart(target), user, tt.opts)
+			if err != nil {
+				t.Fatalf("Compare returned an error: %v", err)
+			}
+
+			if !resultEqual(*result, tt.expected) {
+				t.Errorf("Compare result mismatch.\n%s", detailedComparison(*result, tt.expected))
+			}
+		})
+	}
+}
+
+func TestCompareTreePositions(t *testing.T) {
+	rawChart := func(version, data string) *chart.Chart {
+		return &chart.Chart{Chart: &helmchart.Chart{
+			Metadata: &helmchart.Metadata{Name: "app", Version: version},
+			Raw:      []*helmchart.File{{Name: "values.yaml", Data: []byte(data)}},
+		}}
+	}
+	base := rawChart("1.0.0", "image:\n  tag: \"1.1\"\nlegacy: true\nreplicas: 1\n")
+	target := rawChart("2.0.0", "# image settings\nimage:\n  tag: \"2.4\"\nreplicas: 2\n")
+
+	f, err := values.Parse("prod.yaml", []byte("replicas: 1\nimage:\n  tag: \"1.0\"\nlegacy: false\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := CompareTree(base, target, values.FileTree([]*values.File{f}), Options{})
+	if err != nil {
+		t.Fatalf("CompareTree returned an error: %v", err)
+	}
+
+	expected := []struct {
+		path            string
+		action          Action
+		position        string
+		defaultPosition string
+	}{
+		{path: "image.tag", action: ActionKeep, position: "prod.yaml:3:8", defaultPosition: "app-2.0.0/values.yaml:3:8"},
+		{path: "legacy", action: ActionDelete, position: "prod.yaml:4:9", defaultPosition: "app-1.0.0/values.yaml:3:9"},
+		{path: "replicas", action: ActionSet, position: "prod.yaml:1:11", defaultPosition: "app-2.0.0/values.yaml:4:11"},
+	}
+	if len(result.Decisions) != len(expected) {
+		t.Fatalf("Decisions = %+v, want %d decisions", result.Decisions, len(expected))
+	}
+	for i, want := range expected {
+		got := result.Decisions[i]
+		if got.Path != want.path || got.Action != want.action {
+			t.Errorf("Decisions[%d] = %s %s, want %s %s", i, got.Path, got.Action, want.path, want.action)
+		}
+		if got.Position.String() != want.position || got.DefaultPosition.String() != want.defaultPosition {
+			t.Errorf("Decisions[%d] positions = %s, %s, want %s, %s", i, got.Position, got.DefaultPosition, want.position, want.defaultPosition)
+		}
+	}
+}
+
 func createMockChart(values map[string]interface{}) *chart.Chart {
 	return &chart.Chart{
 		Chart: &helmchart.Chart{
@@ -122,13 +292,40 @@ func createMockChart(values map[string]interface{}) *chart.Chart {
 	}
 }
 
-func resultEqual(a, b Result) bool {
-	return reflect.DeepEqual(a.Added, b.Added) &&
-		reflect.DeepEqual(a.Removed, b.Removed) &&
-		reflect.DeepEqual(a.Modified, b.Modified)
+type changes struct {
+	Added    map[string]interface{}
+	Removed  map[string]interface{}
+	Modified map[string]interface{}
 }
 
-func detailedComparison(got, expected Result) string {
+func decodeChanges(r Result) changes {
+	return changes{Added: decodeNodes(r.Added), Removed: decodeNodes(r.Removed), Modified: decodeNodes(r.Modified)}
+}
+
+func decodeNodes(m map[string]*yaml.Node) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	decoded := make(map[string]interface{}, len(m))
+	for k, node := range m {
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			v = err
+		}
+		decoded[k] = v
+	}
+	return decoded
+}
+
+func resultEqual(a Result, b changes) bool {
+	got := decodeChanges(a)
+	return reflect.DeepEqual(got.Added, b.Added) &&
+		reflect.DeepEqual(got.Removed, b.Removed) &&
+		reflect.DeepEqual(got.Modified, b.Modified)
+}
+
+func detailedComparison(a Result, expected changes) string {
+	got := decodeChanges(a)
 	details := "Detailed comparison:\n"
 	details += compareMap("Added", got.Added, expected.Added)
 	details += compareMap("Removed", got.Removed, expected.Removed)
@@ -189,11 +386,20 @@ func TestShouldKeep(t *testing.T) {
 		{"a.b.c", []string{"a.b.c"}, true},
 		{"a.b.c.d", []string{"a.b.c"}, true},
 		{"a.b", []string{"a.b.c"}, false},
+		{"ab.c", []string{"a"}, false},
+		{`a."b.c"`, []string{"a.b"}, false},
+		{`a."b.c".d`, []string{`a."b.c"`}, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.path, func(t *testing.T) {
-			result := shouldKeep(tt.path, tt.keepValues)
+			path := mustParsePath(t, tt.path)
+			keepValues, err := values.ParsePaths(tt.keepValues)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := shouldKeep(path, keepValues)
 			if result != tt.expected {
 				t.Errorf("shouldKeep(%q, %v) = %v, want %v", tt.path, tt.keepValues, result, tt.expected)
 			}
@@ -201,25 +407,196 @@ func TestShouldKeep(t *testing.T) {
 	}
 }
 
-func TestJoinPath(t *testing.T) {
+func mustParsePath(t *testing.T, s string) values.Path {
+	t.Helper()
+
+	path, err := values.ParsePath(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStaleOverrides(t *testing.T) {
+	result := &Result{
+		Added: encodeNodes(t, map[string]interface{}{
+			"server": map[string]interface{}{"replicas": 1, "image": map[string]interface{}{"tag": "v2"}},
+		}),
+		Removed: encodeNodes(t, map[string]interface{}{
+			"replicas":  1,
+			"legacy":    map[string]interface{}{"enabled": false},
+			"image.tag": "v1",
+		}),
+	}
+
+	expected := []StaleOverride{
+		{Path: values.KeyPath("replicas"), Candidates: []values.Path{values.KeyPath("server", "replicas")}},
+		{Path: values.KeyPath("legacy", "enabled")},
+		{Path: values.KeyPath("image", "tag"), Candidates: []values.Path{values.KeyPath("server", "image", "tag")}},
+	}
+
+	paths := []values.Path{
+		values.KeyPath("replicas"),
+		values.KeyPath("legacy", "enabled"),
+		values.KeyPath("image", "tag"),
+		values.KeyPath("service", "port"),
+	}
+
+	got, err := StaleOverrides(result, paths)
+	if err != nil {
+		t.Fatalf("StaleOverrides returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("StaleOverrides() = %+v, want %+v", got, expected)
+	}
+}
+
+func encodeNodes(t *testing.T, m map[string]interface{}) map[string]*yaml.Node {
+	t.Helper()
+
+	nodes := make(map[string]*yaml.Node, len(m))
+	for k, v := range m {
+		var node yaml.Node
+		if err := node.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		nodes[k] = &node
+	}
+	return nodes
+}
+
+func TestCompareQuotedKeys(t *testing.T) {
+	base := map[string]interface{}{
+		"podAnnotations": map[string]interface{}{"prometheus.io/scrape": "false"},
+	}
+	target := map[string]interface{}{
+		"podAnnotations": map[string]interface{}{"prometheus.io/scrape": "true", "prometheus.io/port": "9090"},
+	}
+
+	result, err := Compare(createMockChart(base), createMockChart(target), map[string]interface{}{}, Options{
+		KeepValues:  []string{`podAnnotations."prometheus.io/port"`},
+		PinDefaults: true,
+	})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+
+	expected := changes{
+		Modified: map[string]interface{}{`podAnnotations."prometheus.io/scrape"`: "true"},
+	}
+	if !resultEqual(*result, expected) {
+		t.Errorf("Compare result mismatch.\n%s", detailedComparison(*result, expected))
+	}
+}
+
+func TestCompareNulledValues(t *testing.T) {
 	tests := []struct {
-		prefix   string
-		key      string
-		expected string
+		name             string
+		base             map[string]interface{}
+		target           map[string]interface{}
+		user             map[string]interface{}
+		expected         changes
+		expectedWarnings []Warning
 	}{
-		{"", "a", "a"},
-		{"a", "b", "a.b"},
-		{"a.b", "c", "a.b.c"},
-		{"", "", ""},
-		{"a.b.c", "", "a.b.c."},
+		{
+			name:     "Nulled scalar is not re-added",
+			base:     map[string]interface{}{"a": 1},
+			target:   map[string]interface{}{"a": 2},
+			user:     map[string]interface{}{"a": nil},
+			expected: changes{},
+		},
+		{
+			name:   "Nulled subtree is preserved",
+			base:   map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
+			target: map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 3}},
+			user:   map[string]interface{}{"a": nil},
+		},
+		{
+			name:   "Nulled subtree restructured in target",
+			base:   map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			target: map[string]interface{}{"a": map[string]interface{}{"b": 1, "d": 4}, "e": 5},
+			user:   map[string]interface{}{"a": nil},
+			expected: changes{
+				Added: map[string]interface{}{"e": 5},
+			},
+			expectedWarnings: []Warning{
+				{Path: "a", Message: "value is nulled out by user values but its defaults were restructured in the target version"},
+			},
+		},
+		{
+			name:     "Nulled key removed in target",
+			base:     map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			target:   map[string]interface{}{},
+			user:     map[string]interface{}{"a": nil},
+			expected: changes{Removed: map[string]interface{}{"a": map[string]interface{}{"b": 1}}},
+		},
+		{
+			name:     "Nulled nested key",
+			base:     map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
+			target:   map[string]interface{}{"a": map[string]interface{}{"b": 2, "c": 3}},
+			user:     map[string]interface{}{"a": map[string]interface{}{"b": nil}},
+			expected: changes{Modified: map[string]interface{}{"a.c": 3}},
+		},
 	}
 
 	for _, tt := range tests {
-		t.Run(fmt.Sprintf("%s+%s", tt.prefix, tt.key), func(t *testing.T) {
-			result := joinPath(tt.prefix, tt.key)
-			if result != tt.expected {
-				t.Errorf("joinPath(%q, %q) = %q, want %q", tt.prefix, tt.key, result, tt.expected)
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Compare(createMockChart(tt.base), createMockChart(tt.target), tt.user, Options{PinDefaults: true})
+			if err != nil {
+				t.Fatalf("Compare returned an error: %v", err)
+			}
+
+			if !resultEqual(*result, tt.expected) {
+				t.Errorf("Compare result mismatch.\n%s", detailedComparison(*result, tt.expected))
+			}
+			if !reflect.DeepEqual(result.Warnings, tt.expectedWarnings) {
+				t.Errorf("Warnings = %+v, want %+v", result.Warnings, tt.expectedWarnings)
 			}
 		})
 	}
 }
+
+func TestCompareDecisions(t *testing.T) {
+	base := map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": map[string]interface{}{"e": 1}, "k": 1, "n": 1}
+	target := map[string]interface{}{"a": 2, "b": 3, "d": map[string]interface{}{"e": 1}, "f": 4, "k": 2, "n": 2}
+	user := map[string]interface{}{"a": 5, "c": 3, "f": 4, "n": nil}
+
+	result, err := Compare(createMockChart(base), createMockChart(target), user, Options{KeepValues: []string{"k"}})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+
+	expected := []Decision{
+		{Path: "a", Change: ChangeModified, Action: ActionKeep, Reason: "user value is kept although the default changed", Base: 1, Target: 2, User: 5, UserSet: true, Conflict: true},
+		{Path: "b", Change: ChangeModified, Action: ActionIgnore, Reason: "chart default changed and is not overridden by user values", Base: 2, Target: 3},
+		{Path: "c", Change: ChangeRemoved, Action: ActionDelete, Reason: "key removed in the target version is deleted from the user values", Base: 3, User: 3, UserSet: true},
+		{Path: "f", Change: ChangeAdded, Action: ActionKeep, Reason: "key added in the target version is already set by user values", Target: 4, User: 4, UserSet: true},
+		{Path: "k", Change: ChangeModified, Action: ActionSkip, Reason: "excluded with --keep", Base: 1, Target: 2},
+		{Path: "n", Change: ChangeModified, Action: ActionSkip, Reason: "value is nulled out by user values", Base: 1, Target: 2, UserSet: true},
+	}
+	if !reflect.DeepEqual(result.Decisions, expected) {
+		t.Errorf("Decisions = %+v, want %+v", result.Decisions, expected)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	m := map[string]*yaml.Node{"b": nil, "a.b-c": nil, "a.b.c": nil, "a": nil, `a."b.c"`: nil, "a.b": nil}
+
+	expected := []string{"a", "a.b", "a.b.c", "a.b-c", `a."b.c"`, "b"}
+	if got := SortedKeys(m); !reflect.DeepEqual(got, expected) {
+		t.Errorf("SortedKeys() = %v, want %v", got, expected)
+	}
+}
+
+func TestCompareTreeDecodeError(t *testing.T) {
+	f, err := values.Parse("values.yaml", []byte("replicas: !!int many\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := createMockChart(map[string]interface{}{"replicas": 1})
+	target := createMockChart(map[string]interface{}{"replicas": 2})
+	if _, err := CompareTree(base, target, values.FileTree([]*values.File{f}), Options{}); err == nil {
+		t.Error("CompareTree() did not fail for a value that cannot be decoded")
+	}
+}
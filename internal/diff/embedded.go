@@ -0,0 +1,204 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+	"gopkg.in/yaml.v3"
+)
+
+type document struct {
+	file   *values.File
+	values map[string]interface{}
+}
+
+type embeddedMerge struct {
+	opts      Options
+	doc       *yaml.Node
+	changes   int
+	conflicts []values.Path
+}
+
+func (c *comparison) mergeEmbedded(path values.Path, n nodes, baseVal, targetVal interface{}) (bool, error) {
+	if c.isPinned(path) {
+		return false, nil
+	}
+
+	userVal, err := c.decode(path, n.user)
+	if err != nil {
+		return false, err
+	}
+
+	marked := hasAnyPrefix(path, c.embeddedPaths)
+	base, ok := parseEmbedded(baseVal, marked)
+	if !ok {
+		return false, nil
+	}
+	target, ok := parseEmbedded(targetVal, marked)
+	if !ok {
+		return false, nil
+	}
+	user, ok := parseEmbedded(userVal, marked)
+	if !ok {
+		return false, nil
+	}
+
+	m := &embeddedMerge{opts: c.opts, doc: user.file.Node}
+	if err := m.merge(nil, base.values, target.values, user.values); err != nil {
+		return false, nil
+	}
+
+	key := path.String()
+	if len(m.conflicts) > 0 {
+		conflicts := make([]string, len(m.conflicts))
+		for i, conflict := range m.conflicts {
+			conflicts[i] = conflict.String()
+		}
+		c.result.Modified[key] = n.user
+		return true, c.decide(path, n, ChangeModified, ActionKeep, fmt.Sprintf("user value is kept because the embedded document has conflicting changes at %s", strings.Join(conflicts, ", ")))
+	}
+
+	if m.changes == 0 {
+		c.result.Modified[key] = n.user
+		d, err := c.decision(path, n, ChangeModified, ActionKeep, "user value already contains the changes of the embedded document")
+		if err != nil {
+			return false, err
+		}
+		d.Conflict = false
+		c.result.Decisions = append(c.result.Decisions, d)
+		return true, nil
+	}
+
+	encoded, err := encodeEmbedded(user.file, userVal.(string))
+	if err != nil {
+		return false, nil
+	}
+	merged := *n.user
+	merged.Value = encoded
+	c.result.Modified[key] = &merged
+	return true, c.decide(path, n, ChangeModified, ActionSet, "changes of the embedded document are merged into the user value")
+}
+
+func parseEmbedded(v interface{}, marked bool) (*document, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+
+	trimmed := strings.TrimSpace(s)
+	if !marked && !strings.Contains(trimmed, "\n") && !(strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed))) {
+		return nil, false
+	}
+
+	f, err := values.Parse("", []byte(s), 0)
+	if err != nil || len(f.Documents) != 1 {
+		return nil, false
+	}
+
+	var m map[string]interface{}
+	if err := f.Node.Decode(&m); err != nil || len(m) == 0 {
+		return nil, false
+	}
+
+	return &document{file: f, values: m}, true
+}
+
+func encodeEmbedded(f *values.File, original string) (string, error) {
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		return "", err
+	}
+
+	if f.Format == values.FormatJSON && !strings.Contains(strings.TrimSpace(original), "\n") {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, buf.Bytes()); err != nil {
+			return "", err
+		}
+		buf = compact
+	}
+
+	s := strings.TrimRight(buf.String(), "\n")
+	if strings.HasSuffix(original, "\n") {
+		s += "\n"
+	}
+	return s, nil
+}
+
+func (m *embeddedMerge) merge(prefix values.Path, base, target, user map[string]interface{}) error {
+	for _, k := range sortedMapKeys(target) {
+		path := prefix.Child(k)
+		targetVal := target[k]
+		baseVal, inBase := base[k]
+		userVal, inUser := user[k]
+
+		if inBase && Equal(baseVal, targetVal, m.opts) {
+			continue
+		}
+
+		baseMap, baseIsMap := baseVal.(map[string]interface{})
+		targetMap, targetIsMap := targetVal.(map[string]interface{})
+		userMap, userIsMap := userVal.(map[string]interface{})
+		if inBase && baseIsMap && targetIsMap && userIsMap {
+			if err := m.merge(path, baseMap, targetMap, userMap); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case inUser && Equal(userVal, targetVal, m.opts):
+		case !inBase && !inUser, inBase && inUser && Equal(userVal, baseVal, m.opts):
+			if err := m.set(path, targetVal); err != nil {
+				return err
+			}
+		default:
+			m.conflicts = append(m.conflicts, path)
+		}
+	}
+
+	for _, k := range sortedMapKeys(base) {
+		if _, inTarget := target[k]; inTarget {
+			continue
+		}
+		path := prefix.Child(k)
+		userVal, inUser := user[k]
+
+		switch {
+		case !inUser:
+		case Equal(userVal, base[k], m.opts):
+			if err := values.DeletePath(m.doc, path); err != nil {
+				return err
+			}
+			m.changes++
+		default:
+			m.conflicts = append(m.conflicts, path)
+		}
+	}
+
+	return nil
+}
+
+func (m *embeddedMerge) set(path values.Path, v interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return err
+	}
+	if err := values.SetNode(m.doc, path, &node); err != nil {
+		return err
+	}
+	m.changes++
+	return nil
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestCompareEmbedded(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		target   string
+		user     string
+		opts     Options
+		merged   string
+		action   Action
+		conflict bool
+	}{
+		{
+			name: "yaml merge",
+			base: `global:
+  resolve_timeout: 5m
+route:
+  receiver: "null"
+  group_wait: 30s
+inhibit_rules: []
+`,
+			target: `global:
+  resolve_timeout: 5m
+route:
+  receiver: "null"
+  group_wait: 10s
+  group_interval: 5m
+`,
+			user: `global:
+  resolve_timeout: 5m
+route:
+  # page the on-call engineer
+  receiver: pager
+  group_wait: 30s
+receivers:
+  - name: pager
+inhibit_rules: []
+`,
+			merged: `global:
+  resolve_timeout: 5m
+route:
+  # page the on-call engineer
+  receiver: pager
+  group_wait: 10s
+  group_interval: 5m
+receivers:
+  - name: pager
+`,
+			action: ActionSet,
+		},
+		{
+			name:   "compact json merge",
+			base:   `{"log_level": "info", "buffer": {"size": 1}}`,
+			target: `{"log_level": "info", "buffer": {"size": 2}}`,
+			user:   `{"log_level": "debug", "buffer": {"size": 1}}`,
+			merged: `{"log_level":"debug","buffer":{"size":2}}`,
+			action: ActionSet,
+		},
+		{
+			name:   "trailing newline is kept",
+			base:   "a: 1\nb: 1",
+			target: "a: 1\nb: 2",
+			user:   "a: 2\nb: 1",
+			merged: "a: 2\nb: 2",
+			action: ActionSet,
+		},
+		{
+			name:     "conflicting change",
+			base:     "route:\n  receiver: default\n  group_wait: 30s\n",
+			target:   "route:\n  receiver: default\n  group_wait: 10s\n",
+			user:     "route:\n  receiver: default\n  group_wait: 1m\n",
+			merged:   "route:\n  receiver: default\n  group_wait: 1m\n",
+			action:   ActionKeep,
+			conflict: true,
+		},
+		{
+			name:   "user already has the change",
+			base:   "a: 1\nb: 1\n",
+			target: "a: 1\nb: 2\n",
+			user:   "a: 2\nb: 2\n",
+			merged: "a: 2\nb: 2\n",
+			action: ActionKeep,
+		},
+		{
+			name:     "single line string is opaque",
+			base:     "{a: 1, b: 1}",
+			target:   "{a: 1, b: 2}",
+			user:     "{a: 2, b: 1}",
+			merged:   "{a: 2, b: 1}",
+			action:   ActionKeep,
+			conflict: true,
+		},
+		{
+			name:   "single line string marked as embedded",
+			base:   "{a: 1, b: 1}",
+			target: "{a: 1, b: 2}",
+			user:   "{a: 2, b: 1}",
+			opts:   Options{EmbeddedPaths: []string{"config"}},
+			merged: "{a: 2, b: 2}",
+			action: ActionSet,
+		},
+		{
+			name:     "pinned value is not merged",
+			base:     "a: 1\nb: 1\n",
+			target:   "a: 1\nb: 2\n",
+			user:     "a: 2\nb: 1\n",
+			opts:     Options{PinUserValues: true},
+			merged:   "a: 2\nb: 1\n",
+			action:   ActionKeep,
+			conflict: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := createMockChart(map[string]interface{}{"config": tt.base})
+			target := createMockChart(map[string]interface{}{"config": tt.target})
+			user := map[string]interface{}{"config": tt.user}
+
+			result, err := Compare(base, target, user, tt.opts)
+			if err != nil {
+				t.Fatalf("Compare returned an error: %v", err)
+			}
+
+			var merged string
+			if node := result.Modified["config"]; node != nil {
+				merged = node.Value
+			}
+			if merged != tt.merged {
+				t.Errorf("merged value:\n%s\nwant:\n%s", merged, tt.merged)
+			}
+
+			if len(result.Decisions) != 1 {
+				t.Fatalf("Decisions = %+v, want one decision", result.Decisions)
+			}
+			decision := result.Decisions[0]
+			if decision.Action != tt.action || decision.Conflict != tt.conflict {
+				t.Errorf("decision = %s (conflict %v), want %s (conflict %v): %s", decision.Action, decision.Conflict, tt.action, tt.conflict, decision.Reason)
+			}
+		})
+	}
+}
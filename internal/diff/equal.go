@@ -0,0 +1,190 @@
+package diff
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type kind int
+
+const (
+	kindNull kind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindMap
+	kindList
+	kindOther
+)
+
+func Equal(a, b interface{}, opts Options) bool {
+	switch kindOf(a) {
+	case kindMap:
+		aMap, bMap := a.(map[string]interface{}), asMap(b)
+		if bMap == nil || len(aMap) != len(bMap) {
+			return false
+		}
+		for k, aVal := range aMap {
+			bVal, ok := bMap[k]
+			if !ok || !Equal(aVal, bVal, opts) {
+				return false
+			}
+		}
+		return true
+	case kindList:
+		aList, bList := a.([]interface{}), asList(b)
+		if bList == nil || len(aList) != len(bList) {
+			return false
+		}
+		for i := range aList {
+			if !Equal(aList[i], bList[i], opts) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	aNumber, aIsNumber := number(a)
+	bNumber, bIsNumber := number(b)
+	if aIsNumber && bIsNumber {
+		return aNumber.Cmp(bNumber) == 0
+	}
+
+	if opts.CaseInsensitiveBools {
+		aBool, aIsBool := boolean(a)
+		bBool, bIsBool := boolean(b)
+		if aIsBool && bIsBool {
+			return aBool == bBool
+		}
+	}
+
+	aString, aIsString := a.(string)
+	bString, bIsString := b.(string)
+	if aIsString && bIsString {
+		if aDuration, err := time.ParseDuration(aString); err == nil {
+			if bDuration, err := time.ParseDuration(bString); err == nil {
+				return aDuration == bDuration
+			}
+		}
+	}
+	if (aIsString && isQuantity(aString)) || (bIsString && isQuantity(bString)) {
+		aQuantity, aOk := quantity(a)
+		bQuantity, bOk := quantity(b)
+		return aOk && bOk && aQuantity.Cmp(bQuantity) == 0
+	}
+
+	return false
+}
+
+func kindOf(v interface{}) kind {
+	switch v.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindBool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return kindNumber
+	case string:
+		return kindString
+	case map[string]interface{}:
+		return kindMap
+	case []interface{}:
+		return kindList
+	}
+	return kindOther
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func asList(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func number(v interface{}) (*big.Rat, bool) {
+	r := new(big.Rat)
+	switch n := v.(type) {
+	case int:
+		return r.SetInt64(int64(n)), true
+	case int8:
+		return r.SetInt64(int64(n)), true
+	case int16:
+		return r.SetInt64(int64(n)), true
+	case int32:
+		return r.SetInt64(int64(n)), true
+	case int64:
+		return r.SetInt64(n), true
+	case uint:
+		return r.SetUint64(uint64(n)), true
+	case uint8:
+		return r.SetUint64(uint64(n)), true
+	case uint16:
+		return r.SetUint64(uint64(n)), true
+	case uint32:
+		return r.SetUint64(uint64(n)), true
+	case uint64:
+		return r.SetUint64(n), true
+	case float32:
+		return float(r, float64(n))
+	case float64:
+		return float(r, n)
+	case json.Number:
+		return r.SetString(n.String())
+	}
+	return nil, false
+}
+
+func float(r *big.Rat, f float64) (*big.Rat, bool) {
+	if r.SetFloat64(f) == nil {
+		return nil, false
+	}
+	return r, true
+}
+
+func boolean(v interface{}) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		switch strings.ToLower(b) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func isQuantity(s string) bool {
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	_, err := resource.ParseQuantity(s)
+	return err == nil
+}
+
+func quantity(v interface{}) (resource.Quantity, bool) {
+	if s, ok := v.(string); ok {
+		q, err := resource.ParseQuantity(s)
+		return q, err == nil
+	}
+	if n, ok := number(v); ok {
+		q, err := resource.ParseQuantity(n.FloatString(9))
+		return q, err == nil
+	}
+	return resource.Quantity{}, false
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        interface{}
+		b        interface{}
+		opts     Options
+		expected bool
+	}{
+		{name: "int and float", a: 1, b: 1.0, expected: true},
+		{name: "int64 and float64", a: int64(3), b: float64(3), expected: true},
+		{name: "json number", a: json.Number("2.50"), b: 2.5, expected: true},
+		{name: "different numbers", a: 1, b: 1.5, expected: false},
+		{name: "number and numeric string", a: 1, b: "1", expected: false},
+		{name: "cpu millicores and cores", a: "500m", b: 0.5, expected: true},
+		{name: "cpu millicores and core string", a: "1000m", b: "1", expected: true},
+		{name: "cpu millicores and decimal string", a: "100m", b: "0.1", expected: true},
+		{name: "memory units", a: "1Gi", b: "1024Mi", expected: true},
+		{name: "quantities with suffixes", a: "1.5Gi", b: "1536Mi", expected: true},
+		{name: "quantity and number", a: "1Ki", b: 1024, expected: true},
+		{name: "different quantity and number", a: "1Ki", b: 1000, expected: false},
+		{name: "different memory", a: "1Gi", b: "1G", expected: false},
+		{name: "quantity and word", a: "1Gi", b: "large", expected: false},
+		{name: "durations", a: "1m", b: "60s", expected: true},
+		{name: "hours and minutes", a: "1h30m", b: "90m", expected: true},
+		{name: "different durations", a: "1m", b: "61s", expected: false},
+		{name: "millicores and duration", a: "500m", b: "30s", expected: false},
+		{name: "plain strings", a: "IfNotPresent", b: "Always", expected: false},
+		{name: "bool and string", a: true, b: "True", expected: false},
+		{name: "case-insensitive bool", a: true, b: "True", opts: Options{CaseInsensitiveBools: true}, expected: true},
+		{name: "case-insensitive bool strings", a: "FALSE", b: "false", opts: Options{CaseInsensitiveBools: true}, expected: true},
+		{name: "case-insensitive different bools", a: "TRUE", b: false, opts: Options{CaseInsensitiveBools: true}, expected: false},
+		{
+			name:     "nested maps",
+			a:        map[string]interface{}{"limits": map[string]interface{}{"cpu": "500m", "memory": "1Gi"}},
+			b:        map[string]interface{}{"limits": map[string]interface{}{"cpu": 0.5, "memory": "1024Mi"}},
+			expected: true,
+		},
+		{
+			name:     "maps with different keys",
+			a:        map[string]interface{}{"a": 1},
+			b:        map[string]interface{}{"b": 1},
+			expected: false,
+		},
+		{name: "lists", a: []interface{}{1, "1Gi"}, b: []interface{}{1.0, "1024Mi"}, expected: true},
+		{name: "lists of different length", a: []interface{}{1}, b: []interface{}{1, 2}, expected: false},
+		{name: "map and scalar", a: map[string]interface{}{}, b: "", expected: false},
+		{name: "nulls", a: nil, b: nil, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.a, tt.b, tt.opts); got != tt.expected {
+				t.Errorf("Equal(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.expected)
+			}
+			if got := Equal(tt.b, tt.a, tt.opts); got != tt.expected {
+				t.Errorf("Equal(%v, %v) = %v, want %v", tt.b, tt.a, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareSemanticEquality(t *testing.T) {
+	base := map[string]interface{}{
+		"replicas":  1,
+		"resources": map[string]interface{}{"cpu": "500m", "memory": "1Gi"},
+		"timeout":   "60s",
+		"enabled":   true,
+	}
+	target := map[string]interface{}{
+		"replicas":  1.0,
+		"resources": map[string]interface{}{"cpu": 0.5, "memory": "1024Mi"},
+		"timeout":   "1m",
+		"enabled":   "True",
+	}
+	user := map[string]interface{}{
+		"replicas": float64(1),
+		"timeout":  "1m0s",
+	}
+
+	result, err := Compare(createMockChart(base), createMockChart(target), user, Options{CaseInsensitiveBools: true})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+
+	if len(result.Decisions) != 0 {
+		t.Errorf("Decisions = %+v, want no changes", result.Decisions)
+	}
+	if result.Defaults != nil || len(result.Modified) != 0 || len(result.Added) != 0 || len(result.Removed) != 0 {
+		t.Errorf("Compare() = %+v, want an empty result", result)
+	}
+
+	result, err = Compare(createMockChart(base), createMockChart(target), user, Options{})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+	if len(result.Decisions) != 1 || result.Decisions[0].Path != "enabled" {
+		t.Errorf("Decisions = %+v, want only the boolean change", result.Decisions)
+	}
+}
@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+	"gopkg.in/yaml.v3"
+)
+
+type Explanation struct {
+	Path      values.Path
+	Base      interface{}
+	InBase    bool
+	Target    interface{}
+	InTarget  bool
+	User      interface{}
+	UserSet   bool
+	Position  values.Position
+	Rules     []string
+	Decisions []Decision
+}
+
+func Explain(base, target *chart.Chart, userValues map[string]interface{}, path values.Path, opts Options) (*Explanation, error) {
+	user, err := values.TreeFromMap(userValues, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return ExplainTree(base, target, user, path, opts)
+}
+
+func ExplainTree(base, target *chart.Chart, user *values.Tree, path values.Path, opts Options) (*Explanation, error) {
+	c, err := newComparison(base, target, user, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.compare(base, target)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Explanation{Path: path}
+	baseNode, inBase := c.base.Lookup(path)
+	targetNode, inTarget := c.target.Lookup(path)
+	userNode, userSet := user.Lookup(path)
+	e.InBase, e.InTarget, e.UserSet, e.Position = inBase, inTarget, userSet, user.Position(userNode)
+	if e.Base, err = c.decode(path, baseNode); err != nil {
+		return nil, err
+	}
+	if e.Target, err = c.decode(path, targetNode); err != nil {
+		return nil, err
+	}
+	if e.User, err = c.decode(path, userNode); err != nil {
+		return nil, err
+	}
+
+	for _, keep := range c.keepPaths {
+		if path.HasPrefix(keep) || keep.HasPrefix(path) {
+			e.Rules = append(e.Rules, fmt.Sprintf("%s is excluded from the upgrade with --keep", keep))
+		}
+	}
+	for _, embedded := range c.embeddedPaths {
+		if path.HasPrefix(embedded) || embedded.HasPrefix(path) {
+			e.Rules = append(e.Rules, fmt.Sprintf("%s is merged as an embedded YAML or JSON document with --embedded", embedded))
+		}
+	}
+	for _, pin := range opts.Pins {
+		if path.HasPrefix(pin) || pin.HasPrefix(path) {
+			e.Rules = append(e.Rules, fmt.Sprintf("%s is pinned with a '# valgrade:pin' comment", pin))
+		}
+	}
+	if opts.PinUserValues {
+		e.Rules = append(e.Rules, "--pin-user-values treats every key in the values files as intentional")
+	}
+	if opts.PinDefaults {
+		e.Rules = append(e.Rules, "--pin-defaults writes changed chart defaults into the values files")
+	}
+	if opts.IgnoreMissing {
+		e.Rules = append(e.Rules, "--ignore-missing keeps keys removed in the target version")
+	}
+	if e.UserSet && e.User == nil {
+		e.Rules = append(e.Rules, "the user values set the key to null, which removes the chart default")
+	}
+
+	if e.UserSet && !overridden(c.userChanges, path) && !c.isPinned(path) && !shouldKeep(path, c.keepPaths) {
+		e.Rules = append(e.Rules, "the user value equals the default of the base version and is updated when the chart default changes")
+	}
+
+	for _, decision := range result.Decisions {
+		decisionPath, err := values.ParsePath(decision.Path)
+		if err != nil {
+			return nil, err
+		}
+		if decisionPath.HasPrefix(path) || path.HasPrefix(decisionPath) {
+			e.Decisions = append(e.Decisions, decision)
+		}
+	}
+
+	return e, nil
+}
+
+func overridden(userChanges map[string]*yaml.Node, path values.Path) bool {
+	for k := range userChanges {
+		changed, err := values.ParsePath(k)
+		if err != nil {
+			continue
+		}
+		if changed.HasPrefix(path) || path.HasPrefix(changed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+func TestExplain(t *testing.T) {
+	base := createMockChart(map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "1.1", "pullPolicy": "IfNotPresent"},
+		"replicas": 1,
+	})
+	target := createMockChart(map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "2.4", "pullPolicy": "Always"},
+		"replicas": 2,
+	})
+	user := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "1.0"},
+		"replicas": 1,
+	}
+
+	tests := []struct {
+		name              string
+		path              string
+		opts              Options
+		expectedRules     []string
+		expectedDecisions []Decision
+	}{
+		{
+			name: "user override",
+			path: "image.tag",
+			expectedDecisions: []Decision{
+				{Path: "image.tag", Change: ChangeModified, Action: ActionKeep, Reason: "user value is kept although the default changed", Base: "1.1", Target: "2.4", User: "1.0", UserSet: true, Conflict: true},
+			},
+		},
+		{
+			name:          "value equal to the base default",
+			path:          "replicas",
+			expectedRules: []string{"the user value equals the default of the base version and is updated when the chart default changes"},
+			expectedDecisions: []Decision{
+				{Path: "replicas", Change: ChangeModified, Action: ActionSet, Reason: "user value equals the default of the base version and is updated to the new default", Base: 1, Target: 2, User: 1, UserSet: true},
+			},
+		},
+		{
+			name:          "pinned value",
+			path:          "replicas",
+			opts:          Options{Pins: []values.Path{values.KeyPath("replicas")}},
+			expectedRules: []string{"replicas is pinned with a '# valgrade:pin' comment"},
+			expectedDecisions: []Decision{
+				{Path: "replicas", Change: ChangeModified, Action: ActionKeep, Reason: "user value is kept although the default changed", Base: 1, Target: 2, User: 1, UserSet: true, Conflict: true},
+			},
+		},
+		{
+			name:          "value equal to the base default with pinned user values",
+			path:          "replicas",
+			opts:          Options{PinUserValues: true},
+			expectedRules: []string{"--pin-user-values treats every key in the values files as intentional"},
+			expectedDecisions: []Decision{
+				{Path: "replicas", Change: ChangeModified, Action: ActionKeep, Reason: "user value is kept although the default changed", Base: 1, Target: 2, User: 1, UserSet: true, Conflict: true},
+			},
+		},
+		{
+			name:          "subtree excluded with keep",
+			path:          "image",
+			opts:          Options{KeepValues: []string{"image"}},
+			expectedRules: []string{"image is excluded from the upgrade with --keep"},
+			expectedDecisions: []Decision{
+				{
+					Path:    "image",
+					Change:  ChangeModified,
+					Action:  ActionSkip,
+					Reason:  "excluded with --keep",
+					Base:    map[string]interface{}{"tag": "1.1", "pullPolicy": "IfNotPresent"},
+					Target:  map[string]interface{}{"tag": "2.4", "pullPolicy": "Always"},
+					User:    map[string]interface{}{"tag": "1.0"},
+					UserSet: true,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := mustParsePath(t, tt.path)
+
+			got, err := Explain(base, target, user, path, tt.opts)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+
+			if !got.InBase || !got.InTarget || !got.UserSet {
+				t.Errorf("Explain() = %+v, want the path to exist in base, target and user values", got)
+			}
+			if !reflect.DeepEqual(got.Rules, tt.expectedRules) {
+				t.Errorf("Rules = %q, want %q", got.Rules, tt.expectedRules)
+			}
+			if !reflect.DeepEqual(got.Decisions, tt.expectedDecisions) {
+				t.Errorf("Decisions = %+v, want %+v", got.Decisions, tt.expectedDecisions)
+			}
+		})
+	}
+}
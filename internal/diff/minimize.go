@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+func Redundant(defaults, user map[string]interface{}, opts Options) []values.Path {
+	paths := redundantPaths(nil, defaults, user, opts)
+	values.SortPaths(paths)
+	return paths
+}
+
+func redundantPaths(prefix values.Path, defaults, user map[string]interface{}, opts Options) []values.Path {
+	var paths []values.Path
+
+	for k, v := range user {
+		path := prefix.Child(k)
+		defaultVal, exists := defaults[k]
+		if !exists {
+			continue
+		}
+
+		userMap, userIsMap := v.(map[string]interface{})
+		defaultMap, defaultIsMap := defaultVal.(map[string]interface{})
+		if userIsMap && defaultIsMap {
+			if len(userMap) == 0 {
+				paths = append(paths, path)
+				continue
+			}
+			paths = append(paths, redundantPaths(path, defaultMap, userMap, opts)...)
+			continue
+		}
+
+		if Equal(v, defaultVal, opts) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+func TestRedundant(t *testing.T) {
+	defaults := map[string]interface{}{
+		"replicas": 1,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.0",
+		},
+		"args":        []interface{}{"--a"},
+		"resources":   map[string]interface{}{},
+		"annotations": map[string]interface{}{"a": "b"},
+		"enabled":     false,
+		"extra":       nil,
+		"memory":      "1Gi",
+		"timeout":     "1m",
+		"workers":     2,
+	}
+
+	user := map[string]interface{}{
+		"replicas": 1,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "2.0",
+		},
+		"args":        []interface{}{"--a"},
+		"resources":   map[string]interface{}{},
+		"annotations": map[string]interface{}{},
+		"enabled":     "false",
+		"extra":       nil,
+		"custom":      "value",
+		"memory":      "1024Mi",
+		"timeout":     "60s",
+		"workers":     2.0,
+	}
+
+	expected := []values.Path{
+		values.KeyPath("annotations"),
+		values.KeyPath("args"),
+		values.KeyPath("extra"),
+		values.KeyPath("image", "repository"),
+		values.KeyPath("memory"),
+		values.KeyPath("replicas"),
+		values.KeyPath("resources"),
+		values.KeyPath("timeout"),
+		values.KeyPath("workers"),
+	}
+
+	if got := Redundant(defaults, user, Options{}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Redundant() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"sort"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+	"gopkg.in/yaml.v3"
+)
+
+const maxSuggestions = 3
+
+type Orphan struct {
+	Path        string
+	Position    values.Position
+	Suggestions []string
+}
+
+func findOrphans(base, target *yaml.Node, user *values.Tree, freeForm, keepPaths []values.Path) []Orphan {
+	freeForm = append([]values.Path{values.KeyPath("global")}, freeForm...)
+	freeForm = append(freeForm, emptyMaps(nil, base)...)
+	freeForm = append(freeForm, emptyMaps(nil, target)...)
+
+	var known []string
+	for _, path := range allPaths(nil, target) {
+		known = append(known, path.String())
+	}
+
+	var orphans []Orphan
+	for _, path := range orphanPaths(nil, base, target, user.Root, freeForm) {
+		if shouldKeep(path, keepPaths) {
+			continue
+		}
+		node, _ := user.Lookup(path)
+		orphans = append(orphans, Orphan{Path: path.String(), Position: user.Position(node), Suggestions: suggest(path.String(), known)})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Path < orphans[j].Path
+	})
+	return orphans
+}
+
+func orphanPaths(prefix values.Path, base, target, user *yaml.Node, freeForm []values.Path) []values.Path {
+	var paths []values.Path
+
+	for i := 0; i+1 < len(user.Content); i += 2 {
+		k, v := user.Content[i].Value, user.Content[i+1]
+		path := prefix.Child(k)
+		if hasAnyPrefix(path, freeForm) {
+			continue
+		}
+
+		baseVal, targetVal := mapValue(base, k), mapValue(target, k)
+		if baseVal == nil && targetVal == nil {
+			paths = append(paths, path)
+			continue
+		}
+
+		if !isMap(v) {
+			continue
+		}
+		if (baseVal != nil && isNull(baseVal)) || (targetVal != nil && isNull(targetVal)) {
+			continue
+		}
+		if (baseVal == nil || !isMap(baseVal)) && (targetVal == nil || !isMap(targetVal)) {
+			continue
+		}
+
+		paths = append(paths, orphanPaths(path, baseVal, targetVal, v, freeForm)...)
+	}
+
+	return paths
+}
+
+func emptyMaps(prefix values.Path, node *yaml.Node) []values.Path {
+	var paths []values.Path
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		path, v := prefix.Child(node.Content[i].Value), node.Content[i+1]
+		if isNull(v) || (isMap(v) && len(v.Content) == 0) {
+			paths = append(paths, path)
+			continue
+		}
+		if isMap(v) {
+			paths = append(paths, emptyMaps(path, v)...)
+		}
+	}
+	return paths
+}
+
+func allPaths(prefix values.Path, node *yaml.Node) []values.Path {
+	var paths []values.Path
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		path, v := prefix.Child(node.Content[i].Value), node.Content[i+1]
+		paths = append(paths, path)
+		if isMap(v) {
+			paths = append(paths, allPaths(path, v)...)
+		}
+	}
+	return paths
+}
+
+func suggest(path string, known []string) []string {
+	type candidate struct {
+		path     string
+		distance int
+	}
+
+	limit := len(path) / 4
+	if limit < 2 {
+		limit = 2
+	}
+
+	var candidates []candidate
+	for _, k := range known {
+		if d := editDistance(path, k); d <= limit {
+			candidates = append(candidates, candidate{path: k, distance: d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].path < candidates[j].path
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].path)
+	}
+	return suggestions
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+)
+
+func TestCompareOrphans(t *testing.T) {
+	base := map[string]interface{}{
+		"replicas":       1,
+		"legacy":         true,
+		"podAnnotations": map[string]interface{}{},
+		"image":          map[string]interface{}{"repository": "nginx", "tag": "1.0"},
+		"resources":      nil,
+	}
+	target := map[string]interface{}{
+		"replicaCount":   1,
+		"podAnnotations": map[string]interface{}{},
+		"image":          map[string]interface{}{"repository": "nginx", "tag": "2.0"},
+		"resources":      nil,
+		"extraEnv":       map[string]interface{}{"A": "1"},
+	}
+	user := map[string]interface{}{
+		"replicas":       2,
+		"replicaCuont":   3,
+		"podAnnotations": map[string]interface{}{"prometheus.io/scrape": "true"},
+		"image":          map[string]interface{}{"tga": "2.0", "registry": map[string]interface{}{"url": "docker.io"}},
+		"resources":      map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}},
+		"extraEnv":       map[string]interface{}{"B": "2"},
+		"global":         map[string]interface{}{"imageRegistry": "example.com"},
+		"kept":           true,
+		"unrelated":      map[string]interface{}{"a": 1},
+	}
+
+	targetChart := createMockChart(target)
+	targetChart.Schema = []byte(`{"type": "object", "properties": {"extraEnv": {"type": "object", "additionalProperties": {"type": "string"}}}}`)
+
+	result, err := Compare(createMockChart(base), targetChart, user, Options{KeepValues: []string{"kept"}})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+
+	expected := []Orphan{
+		{Path: "image.registry"},
+		{Path: "image.tga", Suggestions: []string{"image.tag"}},
+		{Path: "replicaCuont", Suggestions: []string{"replicaCount"}},
+		{Path: "unrelated"},
+	}
+	if !reflect.DeepEqual(result.Orphans, expected) {
+		t.Errorf("Orphans = %+v, want %+v", result.Orphans, expected)
+	}
+}
+
+func TestCompareOrphansInvalidSchema(t *testing.T) {
+	targetChart := createMockChart(map[string]interface{}{"replicas": 1})
+	targetChart.Metadata = &helmchart.Metadata{Name: "app", Version: "2.0.0"}
+	targetChart.Schema = []byte(`{"type": `)
+
+	result, err := Compare(createMockChart(map[string]interface{}{"replicas": 1}), targetChart, map[string]interface{}{"replicaz": 2}, Options{})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0].Message, "values.schema.json of app 2.0.0 is invalid") {
+		t.Errorf("Warnings = %+v, want the invalid schema", result.Warnings)
+	}
+	if len(result.Orphans) != 1 || result.Orphans[0].Path != "replicaz" {
+		t.Errorf("Orphans = %+v, want replicaz", result.Orphans)
+	}
+}
+
+func TestCompareOrphansInSubcharts(t *testing.T) {
+	subchart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "grafana"},
+		Values:   map[string]interface{}{"adminUser": "admin"},
+	}
+	target := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:         "stack",
+			Dependencies: []*helmchart.Dependency{{Name: "grafana", Alias: "dashboards"}},
+		},
+		Values: map[string]interface{}{"dashboards": map[string]interface{}{"enabled": true}},
+	}
+	target.SetDependencies(subchart)
+
+	user := map[string]interface{}{
+		"dashboards": map[string]interface{}{"enabled": false, "adminUser": "root", "adminUsr": "root"},
+	}
+
+	result, err := Compare(&chart.Chart{Chart: target}, &chart.Chart{Chart: target}, user, Options{})
+	if err != nil {
+		t.Fatalf("Compare returned an error: %v", err)
+	}
+
+	expected := []Orphan{
+		{Path: "dashboards.adminUsr", Suggestions: []string{"dashboards.adminUser"}},
+	}
+	if !reflect.DeepEqual(result.Orphans, expected) {
+		t.Errorf("Orphans = %+v, want %+v", result.Orphans, expected)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "abd", 1},
+		{"abc", "acb", 1},
+		{"abc", "", 3},
+		{"replicas", "replicaCount", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b); got != tt.expected {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
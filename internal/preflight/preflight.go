@@ -0,0 +1,206 @@
+package preflight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+)
+
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+type Options struct {
+	KubeVersion string
+	Strict      bool
+}
+
+func Run(base, target *chart.Chart, opts Options) ([]Finding, error) {
+	var findings []Finding
+
+	kubeFindings, err := checkKubeVersion(base.Metadata, target.Metadata, opts.KubeVersion)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, kubeFindings...)
+	findings = append(findings, checkDeprecated(target.Metadata)...)
+	findings = append(findings, checkAPIVersion(base.Metadata, target.Metadata)...)
+	findings = append(findings, checkVersions(base.Metadata, target.Metadata)...)
+	findings = append(findings, checkDependencies(base.Chart, target.Chart)...)
+
+	if opts.Strict {
+		for i := range findings {
+			findings[i].Severity = SeverityError
+		}
+	}
+
+	return findings, nil
+}
+
+func Blocking(findings []Finding) []Finding {
+	var blocking []Finding
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			blocking = append(blocking, finding)
+		}
+	}
+	return blocking
+}
+
+func checkKubeVersion(base, target *helmchart.Metadata, kubeVersion string) ([]Finding, error) {
+	var findings []Finding
+
+	if base.KubeVersion != target.KubeVersion && target.KubeVersion != "" {
+		findings = append(findings, Finding{
+			Check:    "kubeVersion",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("kubeVersion constraint changed from %q to %q", base.KubeVersion, target.KubeVersion),
+		})
+	}
+
+	if kubeVersion == "" || target.KubeVersion == "" {
+		return findings, nil
+	}
+
+	parsed, err := chartutil.ParseKubeVersion(kubeVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kube version: %w", err)
+	}
+	if !chartutil.IsCompatibleRange(target.KubeVersion, parsed.String()) {
+		findings = append(findings, Finding{
+			Check:    "kubeVersion",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("kubernetes %s does not satisfy the kubeVersion constraint %q of the target version", parsed, target.KubeVersion),
+		})
+	}
+
+	return findings, nil
+}
+
+func checkDeprecated(target *helmchart.Metadata) []Finding {
+	if !target.Deprecated {
+		return nil
+	}
+	return []Finding{{
+		Check:    "deprecated",
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("chart %s %s is deprecated", target.Name, target.Version),
+	}}
+}
+
+func checkAPIVersion(base, target *helmchart.Metadata) []Finding {
+	if base.APIVersion == target.APIVersion {
+		return nil
+	}
+	return []Finding{{
+		Check:    "apiVersion",
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("chart apiVersion changed from %s to %s", base.APIVersion, target.APIVersion),
+	}}
+}
+
+func checkVersions(base, target *helmchart.Metadata) []Finding {
+	var findings []Finding
+	if message, ok := versionJump("chart version", base.Version, target.Version); ok {
+		findings = append(findings, Finding{Check: "version", Severity: SeverityWarning, Message: message})
+	}
+	if message, ok := versionJump("appVersion", base.AppVersion, target.AppVersion); ok {
+		findings = append(findings, Finding{Check: "appVersion", Severity: SeverityWarning, Message: message})
+	}
+	return findings
+}
+
+func checkDependencies(base, target *helmchart.Chart) []Finding {
+	baseDependencies := dependencyVersions(base)
+	targetDependencies := dependencyVersions(target)
+
+	keys := make([]string, 0, len(baseDependencies)+len(targetDependencies))
+	for key := range baseDependencies {
+		keys = append(keys, key)
+	}
+	for key := range targetDependencies {
+		if _, ok := baseDependencies[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var findings []Finding
+	for _, key := range keys {
+		baseVersion, inBase := baseDependencies[key]
+		targetVersion, inTarget := targetDependencies[key]
+
+		var message string
+		switch {
+		case !inBase:
+			message = fmt.Sprintf("dependency %s %s added", key, targetVersion)
+		case !inTarget:
+			message = fmt.Sprintf("dependency %s %s removed", key, baseVersion)
+		case baseVersion == targetVersion:
+			continue
+		default:
+			if jump, ok := versionJump("dependency "+key, baseVersion, targetVersion); ok {
+				message = jump
+			} else {
+				message = fmt.Sprintf("dependency %s bumped from %s to %s", key, baseVersion, targetVersion)
+			}
+		}
+
+		findings = append(findings, Finding{Check: "dependencies", Severity: SeverityWarning, Message: message})
+	}
+
+	return findings
+}
+
+func dependencyVersions(c *helmchart.Chart) map[string]string {
+	versions := make(map[string]string)
+	if c.Metadata != nil {
+		for _, d := range c.Metadata.Dependencies {
+			key := d.Name
+			if d.Alias != "" {
+				key = d.Alias
+			}
+			versions[key] = d.Version
+		}
+	}
+	for _, dependency := range c.Dependencies() {
+		versions[chart.DependencyKey(c, dependency)] = dependency.Metadata.Version
+	}
+	return versions
+}
+
+func versionJump(name, from, to string) (string, bool) {
+	fromVersion, err := semver.NewVersion(strings.TrimSpace(from))
+	if err != nil {
+		return "", false
+	}
+	toVersion, err := semver.NewVersion(strings.TrimSpace(to))
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case toVersion.LessThan(fromVersion):
+		return fmt.Sprintf("%s is downgraded from %s to %s", name, from, to), true
+	case toVersion.Major() > fromVersion.Major():
+		return fmt.Sprintf("%s jumps a major version from %s to %s", name, from, to), true
+	case fromVersion.Major() == 0 && toVersion.Major() == 0 && toVersion.Minor() > fromVersion.Minor():
+		return fmt.Sprintf("%s jumps a breaking 0.x version from %s to %s", name, from, to), true
+	}
+	return "", false
+}
@@ -0,0 +1,134 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+)
+
+func testChart(metadata *helmchart.Metadata, dependencies ...*helmchart.Chart) *chart.Chart {
+	c := &helmchart.Chart{Metadata: metadata}
+	c.SetDependencies(dependencies...)
+	return &chart.Chart{Chart: c}
+}
+
+func dependency(name, version string) *helmchart.Chart {
+	return &helmchart.Chart{Metadata: &helmchart.Metadata{Name: name, Version: version}}
+}
+
+func TestRun(t *testing.T) {
+	base := testChart(&helmchart.Metadata{
+		Name:        "app",
+		APIVersion:  "v1",
+		Version:     "1.4.0",
+		AppVersion:  "2.1.0",
+		KubeVersion: ">=1.20.0-0",
+	}, dependency("redis", "17.0.0"), dependency("postgresql", "12.1.0"), dependency("common", "0.1.0"))
+
+	target := testChart(&helmchart.Metadata{
+		Name:        "app",
+		APIVersion:  "v2",
+		Version:     "2.0.0",
+		AppVersion:  "2.3.0",
+		KubeVersion: ">=1.25.0-0",
+		Deprecated:  true,
+	}, dependency("redis", "18.2.0"), dependency("postgresql", "12.2.0"), dependency("memcached", "6.0.0"), dependency("common", "0.2.0"))
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []Finding
+	}{
+		{
+			name: "Warnings",
+			opts: Options{KubeVersion: "1.29.0"},
+			expected: []Finding{
+				{Check: "kubeVersion", Severity: SeverityWarning, Message: `kubeVersion constraint changed from ">=1.20.0-0" to ">=1.25.0-0"`},
+				{Check: "deprecated", Severity: SeverityWarning, Message: "chart app 2.0.0 is deprecated"},
+				{Check: "apiVersion", Severity: SeverityWarning, Message: "chart apiVersion changed from v1 to v2"},
+				{Check: "version", Severity: SeverityWarning, Message: "chart version jumps a major version from 1.4.0 to 2.0.0"},
+				{Check: "dependencies", Severity: SeverityWarning, Message: "dependency common jumps a breaking 0.x version from 0.1.0 to 0.2.0"},
+				{Check: "dependencies", Severity: SeverityWarning, Message: "dependency memcached 6.0.0 added"},
+				{Check: "dependencies", Severity: SeverityWarning, Message: "dependency postgresql bumped from 12.1.0 to 12.2.0"},
+				{Check: "dependencies", Severity: SeverityWarning, Message: "dependency redis jumps a major version from 17.0.0 to 18.2.0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := Run(base, target, tt.opts)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(findings, tt.expected) {
+				t.Errorf("Run() = %v, want %v", findings, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunKubeVersion(t *testing.T) {
+	c := testChart(&helmchart.Metadata{Name: "app", APIVersion: "v2", Version: "1.0.0", KubeVersion: ">=1.25.0-0"})
+
+	tests := []struct {
+		name        string
+		kubeVersion string
+		expected    []Finding
+	}{
+		{name: "No kube version", kubeVersion: ""},
+		{name: "Compatible kube version", kubeVersion: "1.29.0"},
+		{
+			name:        "Incompatible kube version",
+			kubeVersion: "v1.24.3",
+			expected: []Finding{{
+				Check:    "kubeVersion",
+				Severity: SeverityError,
+				Message:  `kubernetes v1.24.3 does not satisfy the kubeVersion constraint ">=1.25.0-0" of the target version`,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := Run(c, c, Options{KubeVersion: tt.kubeVersion})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(findings, tt.expected) {
+				t.Errorf("Run() = %v, want %v", findings, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunStrict(t *testing.T) {
+	base := testChart(&helmchart.Metadata{Name: "app", APIVersion: "v2", Version: "1.0.0", AppVersion: "1.0.0"})
+	target := testChart(&helmchart.Metadata{Name: "app", APIVersion: "v2", Version: "1.1.0", AppVersion: "2.0.0"})
+
+	findings, err := Run(base, target, Options{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(findings) != 1 || len(Blocking(findings)) != 0 {
+		t.Fatalf("Run() = %v, want a single non-blocking finding", findings)
+	}
+
+	findings, err = Run(base, target, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if blocking := Blocking(findings); len(blocking) != 1 || blocking[0].Check != "appVersion" {
+		t.Errorf("Blocking() = %v, want the appVersion finding", blocking)
+	}
+}
+
+func TestRunInvalidKubeVersion(t *testing.T) {
+	c := testChart(&helmchart.Metadata{Name: "app", KubeVersion: ">=1.25.0"})
+	if _, err := Run(c, c, Options{KubeVersion: "latest"}); err == nil {
+		t.Error("Run() expected an error for an invalid kube version")
+	}
+}
@@ -0,0 +1,235 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/textdiff"
+)
+
+const (
+	releaseName      = "release-name"
+	releaseNamespace = "default"
+	diffContext      = 3
+)
+
+type Options struct {
+	KubeVersion string
+	APIVersions []string
+}
+
+type Resource struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Source     string
+	Manifest   string
+}
+
+func (r Resource) ID() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+type Change struct {
+	ID   string
+	Kind string
+	Type ChangeType
+	Diff string
+}
+
+func Render(c *chart.Chart, vals map[string]interface{}, opts Options) (map[string]Resource, error) {
+	caps, err := capabilities(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := chartutil.ProcessDependenciesWithMerge(c.Chart, vals); err != nil {
+		return nil, fmt.Errorf("failed to process chart dependencies: %w", err)
+	}
+
+	renderValues, err := chartutil.ToRenderValuesWithSchemaValidation(c.Chart, vals, chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: releaseNamespace,
+		IsInstall: true,
+	}, caps, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare render values: %w", err)
+	}
+
+	rendered, err := engine.Render(c.Chart, renderValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s %s: %w", c.GetName(), c.GetVersion(), err)
+	}
+
+	return parseResources(rendered)
+}
+
+func capabilities(opts Options) (*chartutil.Capabilities, error) {
+	caps := chartutil.DefaultCapabilities.Copy()
+
+	if opts.KubeVersion != "" {
+		kubeVersion, err := chartutil.ParseKubeVersion(opts.KubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kube version: %w", err)
+		}
+		caps.KubeVersion = *kubeVersion
+	}
+	caps.APIVersions = append(caps.APIVersions, opts.APIVersions...)
+
+	return caps, nil
+}
+
+func parseResources(rendered map[string]string) (map[string]Resource, error) {
+	resources := make(map[string]Resource)
+
+	sources := make([]string, 0, len(rendered))
+	for source := range rendered {
+		if isManifest(source) {
+			sources = append(sources, source)
+		}
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		manifests := releaseutil.SplitManifests(rendered[source])
+		keys := make([]string, 0, len(manifests))
+		for key := range manifests {
+			keys = append(keys, key)
+		}
+		sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+		for _, key := range keys {
+			manifest := manifests[key]
+			resource, ok, err := parseResource(source, manifest)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			if existing, exists := resources[resource.ID()]; exists {
+				resource.Manifest = existing.Manifest + "---\n" + resource.Manifest
+			}
+			resources[resource.ID()] = resource
+		}
+	}
+
+	return resources, nil
+}
+
+func isManifest(source string) bool {
+	name := source[strings.LastIndex(source, "/")+1:]
+	return name != "NOTES.txt" && !strings.HasPrefix(name, "_")
+}
+
+func parseResource(source, manifest string) (Resource, bool, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(manifest), &node); err != nil {
+		return Resource{}, false, fmt.Errorf("failed to parse rendered manifest %s: %w", source, err)
+	}
+	if len(node.Content) == 0 {
+		return Resource{}, false, nil
+	}
+
+	var meta struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"metadata"`
+	}
+	if err := node.Decode(&meta); err != nil {
+		return Resource{}, false, fmt.Errorf("failed to parse rendered manifest %s: %w", source, err)
+	}
+	if meta.Kind == "" {
+		return Resource{}, false, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return Resource{}, false, fmt.Errorf("failed to encode rendered manifest %s: %w", source, err)
+	}
+
+	return Resource{
+		APIVersion: meta.APIVersion,
+		Kind:       meta.Kind,
+		Namespace:  meta.Metadata.Namespace,
+		Name:       meta.Metadata.Name,
+		Source:     source,
+		Manifest:   buf.String(),
+	}, true, nil
+}
+
+func Compare(base, target map[string]Resource) []Change {
+	ids := make(map[string]bool, len(base)+len(target))
+	for id := range base {
+		ids[id] = true
+	}
+	for id := range target {
+		ids[id] = true
+	}
+
+	var changes []Change
+	for id := range ids {
+		baseResource, inBase := base[id]
+		targetResource, inTarget := target[id]
+
+		change := Change{ID: id, Kind: targetResource.Kind, Type: ChangeModified}
+		switch {
+		case !inBase:
+			change.Type = ChangeAdded
+		case !inTarget:
+			change.Kind = baseResource.Kind
+			change.Type = ChangeRemoved
+		case baseResource.Manifest == targetResource.Manifest:
+			continue
+		}
+
+		change.Diff = textdiff.Unified("base/"+id, "target/"+id, baseResource.Manifest, targetResource.Manifest, diffContext)
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
+
+func Disallowed(changes []Change, allowedKinds []string) []Change {
+	allowed := make(map[string]bool, len(allowedKinds))
+	for _, kind := range allowedKinds {
+		allowed[strings.ToLower(kind)] = true
+	}
+
+	var disallowed []Change
+	for _, change := range changes {
+		if !allowed[strings.ToLower(change.Kind)] {
+			disallowed = append(disallowed, change)
+		}
+	}
+	return disallowed
+}
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+)
+
+func testChart(version string, templates map[string]string) *chart.Chart {
+	c := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: version, APIVersion: "v2"},
+		Values:   map[string]interface{}{"replicas": 1, "port": 80},
+	}
+	for name, data := range templates {
+		c.Templates = append(c.Templates, &helmchart.File{Name: "templates/" + name, Data: []byte(data)})
+	}
+	return &chart.Chart{Chart: c}
+}
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  replicas: {{ .Values.replicas }}
+`
+
+const service = `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ .Release.Namespace }}
+spec:
+  ports:
+    - port: {{ .Values.port }}
+`
+
+func TestRender(t *testing.T) {
+	c := testChart("1.0.0", map[string]string{
+		"deployment.yaml": deployment,
+		"service.yaml":    service,
+		"NOTES.txt":       "Installed {{ .Release.Name }}",
+		"_helpers.tpl":    `{{- define "app.name" -}}app{{- end -}}`,
+		"pdb.yaml":        `{{- if .Values.pdb }}kind: PodDisruptionBudget{{ end }}`,
+	})
+
+	resources, err := Render(c, map[string]interface{}{"replicas": 3}, Options{KubeVersion: "v1.29.0"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	var ids []string
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	expected := []string{"Deployment/release-name", "Service/default/release-name"}
+	if len(ids) != len(expected) {
+		t.Fatalf("Render() = %v, want %v", ids, expected)
+	}
+	for _, id := range expected {
+		if _, ok := resources[id]; !ok {
+			t.Errorf("Render() is missing %s", id)
+		}
+	}
+	if !strings.Contains(resources["Deployment/release-name"].Manifest, "replicas: 3") {
+		t.Errorf("Render() manifest = %s, want the user replicas", resources["Deployment/release-name"].Manifest)
+	}
+}
+
+func TestRenderInvalidKubeVersion(t *testing.T) {
+	if _, err := Render(testChart("1.0.0", nil), nil, Options{KubeVersion: "latest"}); err == nil {
+		t.Error("Render() expected an error for an invalid kube version")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base, err := Render(testChart("1.0.0", map[string]string{
+		"deployment.yaml": deployment,
+		"service.yaml":    service,
+	}), map[string]interface{}{}, Options{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	target, err := Render(testChart("2.0.0", map[string]string{
+		"deployment.yaml": deployment,
+		"configmap.yaml":  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+	}), map[string]interface{}{"replicas": 2}, Options{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	changes := Compare(base, target)
+
+	got := make(map[string]ChangeType)
+	for _, change := range changes {
+		got[change.ID] = change.Type
+		if change.Diff == "" {
+			t.Errorf("Compare() change %s has no diff", change.ID)
+		}
+	}
+	expected := map[string]ChangeType{
+		"ConfigMap/config":             ChangeAdded,
+		"Deployment/release-name":      ChangeModified,
+		"Service/default/release-name": ChangeRemoved,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Compare() = %v, want %v", got, expected)
+	}
+
+	disallowed := Disallowed(changes, []string{"deployment", "ConfigMap"})
+	if len(disallowed) != 1 || disallowed[0].ID != "Service/default/release-name" {
+		t.Errorf("Disallowed() = %v, want only the service", disallowed)
+	}
+}
@@ -0,0 +1,252 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+const (
+	collapseThreshold   = 10
+	maxValueLength      = 80
+	maxChangelogEntries = 15
+)
+
+type group struct {
+	key     string
+	changes []Change
+}
+
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## valgrade: %s %s → %s\n\n", r.Chart.Name, r.Chart.Base.version(), r.Chart.Target.version())
+	if r.Chart.Repository != "" {
+		fmt.Fprintf(&b, "repository: %s", code(r.Chart.Repository))
+		if r.Chart.Base.AppVersion != "" || r.Chart.Target.AppVersion != "" {
+			fmt.Fprintf(&b, ", app version: %s → %s", code(r.Chart.Base.AppVersion), code(r.Chart.Target.AppVersion))
+		}
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("| added | removed | modified | conflicts | warnings | errors |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %d |\n\n", r.Summary.Added, r.Summary.Removed, r.Summary.Modified, r.Summary.Conflicts, r.Summary.Warnings, r.Summary.Errors)
+
+	r.markdownErrors(&b)
+	r.markdownConflicts(&b)
+	r.markdownChanges(&b)
+	r.markdownWarnings(&b)
+	r.markdownChangelog(&b)
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func (r *Report) markdownErrors(b *strings.Builder) {
+	if len(r.Errors) == 0 {
+		return
+	}
+	b.WriteString("> [!CAUTION]\n")
+	b.WriteString("> **the upgrade failed**\n")
+	for _, err := range r.Errors {
+		fmt.Fprintf(b, "> - %s\n", singleLine(err))
+	}
+	b.WriteString("\n")
+}
+
+func (r *Report) markdownConflicts(b *strings.Builder) {
+	var conflicts []Change
+	for _, change := range r.Changes {
+		if change.Conflict {
+			conflicts = append(conflicts, change)
+		}
+	}
+	if len(conflicts) == 0 {
+		return
+	}
+
+	b.WriteString("> [!WARNING]\n")
+	if len(conflicts) == 1 {
+		b.WriteString("> **1 value differs from the new chart default and was kept, review it before merging**\n")
+	} else {
+		fmt.Fprintf(b, "> **%d values differ from the new chart defaults and were kept, review them before merging**\n", len(conflicts))
+	}
+	for _, change := range conflicts {
+		fmt.Fprintf(b, "> - %s: yours %s, default %s → %s\n", code(change.Path), value(change.User), value(change.Base), value(change.Target))
+		for _, entry := range change.Changelog {
+			fmt.Fprintf(b, ">   - %s\n", singleLine(entry))
+		}
+	}
+	b.WriteString("\n")
+}
+
+func (r *Report) markdownChanges(b *strings.Builder) {
+	b.WriteString("### value changes\n\n")
+	if len(r.Changes) == 0 {
+		b.WriteString("the chart defaults did not change.\n\n")
+		return
+	}
+
+	var inline []Change
+	var collapsed []group
+	for _, g := range groupChanges(r.Changes) {
+		if len(g.changes) > collapseThreshold {
+			collapsed = append(collapsed, g)
+			continue
+		}
+		inline = append(inline, g.changes...)
+	}
+
+	if len(inline) > 0 {
+		changesTable(b, inline)
+		b.WriteString("\n")
+	}
+	for _, g := range collapsed {
+		b.WriteString("<details>\n")
+		fmt.Fprintf(b, "<summary><code>%s</code> (%d changes)</summary>\n\n", html(g.key), len(g.changes))
+		changesTable(b, g.changes)
+		b.WriteString("\n</details>\n\n")
+	}
+}
+
+func (r *Report) markdownWarnings(b *strings.Builder) {
+	if len(r.Warnings) == 0 {
+		return
+	}
+
+	b.WriteString("### warnings\n\n")
+	b.WriteString("| category | path | message |\n")
+	b.WriteString("|---|---|---|\n")
+	for _, warning := range r.Warnings {
+		path := ""
+		if warning.Path != "" {
+			path = code(warning.Path)
+		}
+		fmt.Fprintf(b, "| %s | %s | %s |\n", warning.Category, path, cell(warning.Message))
+	}
+	b.WriteString("\n")
+}
+
+func (r *Report) markdownChangelog(b *strings.Builder) {
+	if len(r.Changelog) == 0 {
+		return
+	}
+
+	b.WriteString("### changelog\n\n")
+	for _, release := range r.Changelog {
+		fmt.Fprintf(b, "#### %s\n\n", release.Version)
+		for i, change := range release.Changes {
+			if i == maxChangelogEntries {
+				fmt.Fprintf(b, "- … and %d more\n", len(release.Changes)-maxChangelogEntries)
+				break
+			}
+			if change.Kind != "" {
+				fmt.Fprintf(b, "- **%s**: %s\n", change.Kind, singleLine(change.Description))
+			} else {
+				fmt.Fprintf(b, "- %s\n", singleLine(change.Description))
+			}
+		}
+		b.WriteString("\n")
+	}
+}
+
+func groupChanges(changes []Change) []group {
+	var groups []group
+	index := make(map[string]int)
+	for _, change := range changes {
+		key := change.Path
+		if path, err := values.ParsePath(change.Path); err == nil && len(path) > 0 {
+			key = path[:1].String()
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, group{key: key})
+		}
+		groups[i].changes = append(groups[i].changes, change)
+	}
+	return groups
+}
+
+func changesTable(b *strings.Builder, changes []Change) {
+	notes := false
+	for _, change := range changes {
+		notes = notes || len(change.Changelog) > 0
+	}
+
+	if notes {
+		b.WriteString("| path | change | action | base | target | yours | changelog |\n")
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+	} else {
+		b.WriteString("| path | change | action | base | target | yours |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+	}
+	for _, change := range changes {
+		user := ""
+		if change.UserSet {
+			user = value(change.User)
+		}
+		path := code(change.Path)
+		if change.Conflict {
+			path += " ⚠️"
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |", path, change.Type, change.Action, value(change.Base), value(change.Target), user)
+		if notes {
+			entries := make([]string, 0, len(change.Changelog))
+			for _, entry := range change.Changelog {
+				entries = append(entries, cell(entry))
+			}
+			fmt.Fprintf(b, " %s |", strings.Join(entries, "<br>"))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func (rel Release) version() string {
+	if rel.Resolved != "" {
+		return rel.Resolved
+	}
+	return rel.Requested
+}
+
+func value(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return code(fmt.Sprintf("%v", v))
+	}
+	s := string(data)
+	if runes := []rune(s); len(runes) > maxValueLength {
+		s = string(runes[:maxValueLength-1]) + "…"
+	}
+	return code(s)
+}
+
+func code(s string) string {
+	s = cell(s)
+	if s == "" {
+		return ""
+	}
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+func cell(s string) string {
+	return strings.ReplaceAll(singleLine(s), "|", `\|`)
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func html(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cstanislawski/helm-valgrade/internal/changelog"
+	"github.com/cstanislawski/helm-valgrade/internal/diff"
+)
+
+func TestMarkdown(t *testing.T) {
+	got := testReport().Markdown()
+
+	expected := []string{
+		"## valgrade: app 1.0.0 → 2.0.0",
+		"| 1 | 1 | 2 | 1 | 2 | 1 |",
+		"> [!CAUTION]\n> **the upgrade failed**\n> - schema violation at replicas: Invalid type",
+		"> [!WARNING]\n> **1 value differs from the new chart default and was kept, review it before merging**\n> - `image.tag`: yours `\"1.0\"`, default `\"1.1\"` → `\"2.4\"`",
+		"| `image.tag` ⚠️ | modified | keep | `\"1.1\"` | `\"2.4\"` | `\"1.0\"` |",
+		"| `resources` | added | ignore |  | `{\"limits\":{\"cpu\":\"1\"}}` |  |",
+		"| orphan | `imagee.tag` | value is not defined by the chart |",
+		"| preflight |  | chart is deprecated |",
+		"#### 2.0.0\n\n- **changed**: Bump app to 2.4",
+	}
+	for _, e := range expected {
+		if !strings.Contains(got, e) {
+			t.Errorf("Markdown() does not contain %q:\n%s", e, got)
+		}
+	}
+	if strings.Contains(got, "<details>") {
+		t.Errorf("Markdown() collapsed a small subtree:\n%s", got)
+	}
+}
+
+func TestMarkdownCollapsesLargeSubtrees(t *testing.T) {
+	r := New("app", "", "1.0.0", "2.0.0", nil)
+
+	var decisions []diff.Decision
+	for i := 0; i <= collapseThreshold; i++ {
+		decisions = append(decisions, diff.Decision{Path: fmt.Sprintf("prometheus.rules.rule%02d", i), Change: diff.ChangeAdded, Action: diff.ActionIgnore, Target: i})
+	}
+	decisions = append(decisions, diff.Decision{Path: "replicas", Change: diff.ChangeModified, Action: diff.ActionIgnore, Base: 1, Target: 2})
+	decisions = append(decisions, diff.Decision{Path: "note", Change: diff.ChangeAdded, Action: diff.ActionIgnore, Target: "a | b\nc"})
+	r.AddDecisions(decisions)
+
+	got := r.Markdown()
+
+	if !strings.Contains(got, "<details>\n<summary><code>prometheus</code> (11 changes)</summary>") {
+		t.Errorf("Markdown() did not collapse the large subtree:\n%s", got)
+	}
+	if strings.Index(got, "| `replicas` |") > strings.Index(got, "<details>") {
+		t.Errorf("Markdown() did not list small subtrees before collapsed ones:\n%s", got)
+	}
+	if !strings.Contains(got, "`\"a \\| b\\nc\"`") {
+		t.Errorf("Markdown() did not escape the table cell:\n%s", got)
+	}
+}
+
+func TestMarkdownLinksChangelog(t *testing.T) {
+	r := New("app", "", "1.0.0", "2.0.0", nil)
+	r.AddChangelog(changelog.Release{Version: "2.0.0", Changes: []changelog.Change{{Description: "Default image.tag is now 2.4"}}})
+	r.AddDecisions([]diff.Decision{
+		{Path: "image.tag", Change: diff.ChangeModified, Action: diff.ActionKeep, Base: "1.1", Target: "2.4", User: "1.0", UserSet: true, Conflict: true},
+		{Path: "replicas", Change: diff.ChangeModified, Action: diff.ActionIgnore, Base: 1, Target: 2},
+	})
+
+	if len(r.Changes[0].Changelog) != 1 || len(r.Changes[1].Changelog) != 0 {
+		t.Fatalf("Changes = %+v, want the changelog entry linked to image.tag only", r.Changes)
+	}
+
+	got := r.Markdown()
+	for _, e := range []string{
+		">   - 2.0.0: Default image.tag is now 2.4",
+		"| path | change | action | base | target | yours | changelog |",
+		"| `image.tag` ⚠️ | modified | keep | `\"1.1\"` | `\"2.4\"` | `\"1.0\"` | 2.0.0: Default image.tag is now 2.4 |",
+		"| `replicas` | modified | ignore | `1` | `2` |  |  |",
+	} {
+		if !strings.Contains(got, e) {
+			t.Errorf("Markdown() does not contain %q:\n%s", e, got)
+		}
+	}
+}
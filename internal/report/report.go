@@ -0,0 +1,243 @@
+package report
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cstanislawski/helm-valgrade/internal/changelog"
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/diff"
+)
+
+const Version = "v1"
+
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatYAML     Format = "yaml"
+	FormatMarkdown Format = "markdown"
+)
+
+var Formats = []Format{FormatJSON, FormatYAML, FormatMarkdown}
+
+const (
+	CategoryPreflight = "preflight"
+	CategoryValue     = "value"
+	CategoryOverride  = "override"
+	CategoryOrphan    = "orphan"
+	CategorySchema    = "schema"
+	CategoryTemplate  = "template"
+	CategoryCRD       = "crd"
+	CategoryRender    = "render"
+)
+
+//go:embed report.schema.json
+var schema []byte
+
+type Report struct {
+	ReportVersion string   `json:"reportVersion" yaml:"reportVersion"`
+	Chart         Chart    `json:"chart" yaml:"chart"`
+	ValuesFiles   []string `json:"valuesFiles" yaml:"valuesFiles"`
+	Summary       Summary  `json:"summary" yaml:"summary"`
+	Changes       []Change `json:"changes" yaml:"changes"`
+	Warnings      []Entry  `json:"warnings" yaml:"warnings"`
+	Errors        []string `json:"errors" yaml:"errors"`
+
+	Changelog []changelog.Release `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+}
+
+type Chart struct {
+	Name       string  `json:"name" yaml:"name"`
+	Repository string  `json:"repository" yaml:"repository"`
+	Base       Release `json:"base" yaml:"base"`
+	Target     Release `json:"target" yaml:"target"`
+}
+
+type Release struct {
+	Requested  string `json:"requested" yaml:"requested"`
+	Resolved   string `json:"resolved,omitempty" yaml:"resolved,omitempty"`
+	AppVersion string `json:"appVersion,omitempty" yaml:"appVersion,omitempty"`
+}
+
+type Summary struct {
+	Added     int `json:"added" yaml:"added"`
+	Removed   int `json:"removed" yaml:"removed"`
+	Modified  int `json:"modified" yaml:"modified"`
+	Conflicts int `json:"conflicts" yaml:"conflicts"`
+	Warnings  int `json:"warnings" yaml:"warnings"`
+	Errors    int `json:"errors" yaml:"errors"`
+}
+
+type Change struct {
+	Path     string      `json:"path" yaml:"path"`
+	Type     string      `json:"type" yaml:"type"`
+	Action   string      `json:"action" yaml:"action"`
+	Reason   string      `json:"reason" yaml:"reason"`
+	Base     interface{} `json:"base,omitempty" yaml:"base,omitempty"`
+	Target   interface{} `json:"target,omitempty" yaml:"target,omitempty"`
+	User     interface{} `json:"user,omitempty" yaml:"user,omitempty"`
+	UserSet  bool        `json:"userSet" yaml:"userSet"`
+	Conflict bool        `json:"conflict" yaml:"conflict"`
+
+	Source        string `json:"source,omitempty" yaml:"source,omitempty"`
+	DefaultSource string `json:"defaultSource,omitempty" yaml:"defaultSource,omitempty"`
+
+	Changelog []string `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+}
+
+type Entry struct {
+	Category string `json:"category" yaml:"category"`
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+func New(name, repository, baseVersion, targetVersion string, valuesFiles []string) *Report {
+	return &Report{
+		ReportVersion: Version,
+		Chart: Chart{
+			Name:       name,
+			Repository: repository,
+			Base:       Release{Requested: baseVersion},
+			Target:     Release{Requested: targetVersion},
+		},
+		ValuesFiles: append([]string{}, valuesFiles...),
+		Changes:     []Change{},
+		Warnings:    []Entry{},
+		Errors:      []string{},
+	}
+}
+
+func Schema() []byte {
+	return schema
+}
+
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == strings.ToLower(s) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown report format %q", s)
+}
+
+func FormatFromFilename(filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".md", ".markdown":
+		return FormatMarkdown
+	default:
+		return FormatJSON
+	}
+}
+
+func (r *Report) Resolve(base, target *chart.Chart) {
+	if base != nil && base.Metadata != nil {
+		r.Chart.Base.Resolved = base.GetVersion()
+		r.Chart.Base.AppVersion = base.Metadata.AppVersion
+	}
+	if target != nil && target.Metadata != nil {
+		r.Chart.Target.Resolved = target.GetVersion()
+		r.Chart.Target.AppVersion = target.Metadata.AppVersion
+	}
+}
+
+func (r *Report) AddDecisions(decisions []diff.Decision) {
+	for _, d := range decisions {
+		r.Changes = append(r.Changes, Change{
+			Path:     d.Path,
+			Type:     string(d.Change),
+			Action:   string(d.Action),
+			Reason:   d.Reason,
+			Base:     d.Base,
+			Target:   d.Target,
+			User:     d.User,
+			UserSet:  d.UserSet,
+			Conflict: d.Conflict,
+
+			Source:        d.Position.String(),
+			DefaultSource: d.DefaultPosition.String(),
+		})
+
+		switch d.Change {
+		case diff.ChangeAdded:
+			r.Summary.Added++
+		case diff.ChangeRemoved:
+			r.Summary.Removed++
+		case diff.ChangeModified:
+			r.Summary.Modified++
+		}
+		if d.Conflict {
+			r.Summary.Conflicts++
+		}
+	}
+	r.linkChangelog()
+}
+
+func (r *Report) AddChangelog(releases ...changelog.Release) {
+	r.Changelog = append(r.Changelog, releases...)
+	r.linkChangelog()
+}
+
+func (r *Report) linkChangelog() {
+	for i := range r.Changes {
+		r.Changes[i].Changelog = changelog.Related(r.Changelog, r.Changes[i].Path)
+	}
+}
+
+func (r *Report) Warn(category, path, message string) {
+	r.Warnings = append(r.Warnings, Entry{Category: category, Path: path, Message: message})
+	r.Summary.Warnings++
+}
+
+func (r *Report) Fail(err error) {
+	r.Errors = append(r.Errors, err.Error())
+	r.Summary.Errors++
+}
+
+func (r *Report) Encode(w io.Writer, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		if _, err := io.WriteString(w, r.Markdown()); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		return nil
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		return encoder.Close()
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		return nil
+	}
+}
+
+func (r *Report) Write(filename string, format Format) error {
+	if filename == "" {
+		return r.Encode(os.Stdout, format)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer f.Close()
+
+	return r.Encode(f, format)
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/changelog"
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/diff"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+func testReport() *Report {
+	r := New("app", "https://charts.example.com", "1.0.0", "2.0.0", []string{"values.yaml"})
+	r.Resolve(
+		&chart.Chart{Chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app", Version: "1.0.0", AppVersion: "1.1"}}},
+		&chart.Chart{Chart: &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app", Version: "2.0.0", AppVersion: "2.4"}}},
+	)
+	r.AddDecisions([]diff.Decision{
+		{Path: "image.tag", Change: diff.ChangeModified, Action: diff.ActionKeep, Reason: "kept", Base: "1.1", Target: "2.4", User: "1.0", UserSet: true, Conflict: true, Position: values.Position{File: "values.yaml", Line: 3, Column: 8}, DefaultPosition: values.Position{File: "app-2.0.0/values.yaml", Line: 12, Column: 8}},
+		{Path: "resources", Change: diff.ChangeAdded, Action: diff.ActionIgnore, Reason: "default", Target: map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}}},
+		{Path: "legacy", Change: diff.ChangeRemoved, Action: diff.ActionDelete, Reason: "removed", Base: true, User: false, UserSet: true},
+		{Path: "tolerations", Change: diff.ChangeModified, Action: diff.ActionSkip, Reason: "nulled", Base: []interface{}{}, UserSet: true},
+	})
+	r.Warn(CategoryOrphan, "imagee.tag", "value is not defined by the chart")
+	r.Warn(CategoryPreflight, "", "chart is deprecated")
+	r.Fail(errors.New("schema violation at replicas: Invalid type"))
+	r.AddChangelog(changelog.Release{Version: "2.0.0", Changes: []changelog.Change{{Kind: "changed", Description: "Bump app to 2.4"}}})
+	return r
+}
+
+func TestReport(t *testing.T) {
+	r := testReport()
+
+	expected := Summary{Added: 1, Removed: 1, Modified: 2, Conflicts: 1, Warnings: 2, Errors: 1}
+	if r.Summary != expected {
+		t.Errorf("Summary = %+v, want %+v", r.Summary, expected)
+	}
+	if r.Chart.Target.Resolved != "2.0.0" || r.Chart.Target.AppVersion != "2.4" {
+		t.Errorf("Chart.Target = %+v", r.Chart.Target)
+	}
+	if r.Changes[0].Source != "values.yaml:3:8" || r.Changes[0].DefaultSource != "app-2.0.0/values.yaml:12:8" || r.Changes[1].Source != "" {
+		t.Errorf("Changes sources = %q, %q, %q", r.Changes[0].Source, r.Changes[0].DefaultSource, r.Changes[1].Source)
+	}
+}
+
+func TestEncodeMatchesSchema(t *testing.T) {
+	for _, r := range []*Report{New("app", "repo", "1.0.0", "2.0.0", nil), testReport()} {
+		for _, format := range []Format{FormatJSON, FormatYAML} {
+			var buf bytes.Buffer
+			if err := r.Encode(&buf, format); err != nil {
+				t.Fatalf("Encode(%s) error = %v", format, err)
+			}
+
+			var document interface{}
+			if format == FormatYAML {
+				if err := yaml.Unmarshal(buf.Bytes(), &document); err != nil {
+					t.Fatalf("Encode(%s) produced invalid YAML: %v", format, err)
+				}
+			} else if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+				t.Fatalf("Encode(%s) produced invalid JSON: %v", format, err)
+			}
+
+			result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(Schema()), gojsonschema.NewGoLoader(document))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			for _, e := range result.Errors() {
+				t.Errorf("Encode(%s) does not match the report schema: %s", format, e)
+			}
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Format
+		wantErr  bool
+	}{
+		{input: "json", expected: FormatJSON},
+		{input: "YAML", expected: FormatYAML},
+		{input: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFormat(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseFormat() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if FormatFromFilename("report.yml") != FormatYAML || FormatFromFilename("report.json") != FormatJSON || FormatFromFilename("summary.md") != FormatMarkdown {
+		t.Error("FormatFromFilename() did not detect the format from the extension")
+	}
+}
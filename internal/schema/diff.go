@@ -0,0 +1,283 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+type ChangeKind string
+
+const (
+	ChangeRequired ChangeKind = "required"
+	ChangeType     ChangeKind = "type"
+	ChangeEnum     ChangeKind = "enum"
+	ChangeInvalid  ChangeKind = "invalid"
+)
+
+type Change struct {
+	Path    values.Path
+	Kind    ChangeKind
+	Message string
+}
+
+func Diff(base, target *chart.Chart, userValues map[string]interface{}) []Change {
+	changes := diffChart(nil, base.Chart, target.Chart, userValues, target.GetAllDefaultValues())
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if a, b := changes[i].Path.String(), changes[j].Path.String(); a != b {
+			return a < b
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes
+}
+
+func diffChart(prefix values.Path, base, target *helmchart.Chart, userValues, defaults map[string]interface{}) []Change {
+	var changes []Change
+
+	var baseSchema *Schema
+	var invalid []string
+	if base != nil {
+		s, err := Parse(base.Schema)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("the schema of %s %s is invalid: %v", base.Name(), base.Metadata.Version, err))
+		}
+		baseSchema = s
+	}
+
+	targetSchema, err := Parse(target.Schema)
+	if err != nil {
+		invalid = append(invalid, fmt.Sprintf("the schema of %s %s is invalid: %v", target.Name(), target.Metadata.Version, err))
+	}
+
+	switch {
+	case len(invalid) > 0:
+		changes = append(changes, Change{
+			Path:    prefix,
+			Kind:    ChangeInvalid,
+			Message: fmt.Sprintf("%s, schema changes are not compared", strings.Join(invalid, ", ")),
+		})
+	case targetSchema != nil:
+		changes = diffSchema(prefix, baseSchema, targetSchema, userValues, true, defaults, true, 0)
+	}
+
+	for _, dependency := range target.Dependencies() {
+		key := chart.DependencyKey(target, dependency)
+		userMap, _ := userValues[key].(map[string]interface{})
+		defaultMap, _ := defaults[key].(map[string]interface{})
+
+		changes = append(changes, diffChart(prefix.Child(key), findDependency(base, key), dependency, userMap, defaultMap)...)
+	}
+
+	return changes
+}
+
+func findDependency(c *helmchart.Chart, key string) *helmchart.Chart {
+	if c == nil {
+		return nil
+	}
+	for _, dependency := range c.Dependencies() {
+		if chart.DependencyKey(c, dependency) == key {
+			return dependency
+		}
+	}
+	return nil
+}
+
+func diffSchema(path values.Path, base, target *Schema, user interface{}, userSet bool, defaults interface{}, defaultSet bool, depth int) []Change {
+	base, target = base.resolve(), target.resolve()
+	if target == nil || depth > 64 {
+		return nil
+	}
+
+	var changes []Change
+
+	if userSet && len(path) > 0 {
+		if change, ok := typeChange(path, base, target, user); ok {
+			changes = append(changes, change)
+		}
+		if change, ok := enumChange(path, base, target, user); ok {
+			changes = append(changes, change)
+		}
+	}
+
+	userMap, _ := user.(map[string]interface{})
+	defaultMap, _ := defaults.(map[string]interface{})
+
+	if (userSet && userMap != nil) || (defaultSet && defaultMap != nil) || len(path) == 0 {
+		for _, name := range target.Required {
+			if base != nil && contains(base.Required, name) {
+				continue
+			}
+			if _, ok := userMap[name]; ok {
+				continue
+			}
+			if _, ok := defaultMap[name]; ok {
+				continue
+			}
+			changes = append(changes, Change{
+				Path:    path.Child(name),
+				Kind:    ChangeRequired,
+				Message: "key is now required by the target version and must be provided",
+			})
+		}
+	}
+
+	for name, child := range target.Properties {
+		userChild, userChildSet := userMap[name]
+		defaultChild, defaultChildSet := defaultMap[name]
+		if !userChildSet && !defaultChildSet {
+			continue
+		}
+		changes = append(changes, diffSchema(path.Child(name), base.property(name), child, userChild, userChildSet, defaultChild, defaultChildSet, depth+1)...)
+	}
+
+	if items, ok := user.([]interface{}); ok && userSet {
+		baseItems, targetItems := base.ItemSchema(), target.ItemSchema()
+		for i, item := range items {
+			changes = append(changes, diffSchema(path.Item(i), baseItems, targetItems, item, true, nil, false, depth+1)...)
+		}
+	}
+
+	return changes
+}
+
+func (s *Schema) property(name string) *Schema {
+	s = s.resolve()
+	if s == nil {
+		return nil
+	}
+	if child, ok := s.Properties[name]; ok {
+		return child
+	}
+	if s.AdditionalProperties != nil {
+		return s.AdditionalProperties.Schema
+	}
+	return nil
+}
+
+func typeChange(path values.Path, base, target *Schema, user interface{}) (Change, bool) {
+	if len(target.Type) == 0 || (base != nil && sameTypes(base.Type, target.Type)) {
+		return Change{}, false
+	}
+
+	valid := matchesType(target.Type, user)
+	typed := base != nil && len(base.Type) > 0
+	if !typed && valid {
+		return Change{}, false
+	}
+
+	message := fmt.Sprintf("type is now %s", strings.Join(target.Type, " or "))
+	if typed {
+		message = fmt.Sprintf("type changed from %s to %s", strings.Join(base.Type, " or "), strings.Join(target.Type, " or "))
+	}
+	if !valid {
+		message += fmt.Sprintf(", the current %s value is no longer valid", jsonType(user))
+	}
+
+	return Change{Path: path, Kind: ChangeType, Message: message}, true
+}
+
+func enumChange(path values.Path, base, target *Schema, user interface{}) (Change, bool) {
+	if len(target.Enum) == 0 {
+		return Change{}, false
+	}
+
+	allowed := containsValue(target.Enum, user)
+
+	var removed []interface{}
+	if base != nil && len(base.Enum) > 0 {
+		for _, v := range base.Enum {
+			if !containsValue(target.Enum, v) {
+				removed = append(removed, v)
+			}
+		}
+		if len(removed) == 0 {
+			return Change{}, false
+		}
+	} else if allowed {
+		return Change{}, false
+	}
+
+	message := fmt.Sprintf("allowed values are now %v", target.Enum)
+	if len(removed) > 0 {
+		message = fmt.Sprintf("allowed values narrowed to %v, removed %v", target.Enum, removed)
+	}
+	if !allowed {
+		message += fmt.Sprintf(", the current value %v is no longer allowed", user)
+	}
+
+	return Change{Path: path, Kind: ChangeEnum, Message: message}, true
+}
+
+func sameTypes(a, b Types) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, typ := range a {
+		if !b.Has(typ) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesType(types Types, v interface{}) bool {
+	typ := jsonType(v)
+	return types.Has(typ) || (typ == "integer" && types.Has("number"))
+}
+
+func jsonType(v interface{}) string {
+	switch typed := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32:
+		return jsonNumberType(float64(typed))
+	case float64:
+		return jsonNumberType(typed)
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func jsonNumberType(f float64) string {
+	if f == math.Trunc(f) {
+		return "integer"
+	}
+	return "number"
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if fmt.Sprint(item) == fmt.Sprint(v) && jsonType(item) == jsonType(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+)
+
+func TestDiff(t *testing.T) {
+	base := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: "1.0.0"},
+		Values: map[string]interface{}{
+			"service": map[string]interface{}{"type": "ClusterIP", "port": 80},
+			"image":   map[string]interface{}{"tag": "1.0"},
+		},
+		Schema: []byte(`{
+  "type": "object",
+  "properties": {
+    "service": {
+      "type": "object",
+      "properties": {
+        "type": {"enum": ["ClusterIP", "NodePort", "LoadBalancer"]},
+        "port": {"type": ["integer", "string"]}
+      }
+    },
+    "image": {"type": "object", "properties": {"tag": {"type": "string"}}},
+    "replicas": {"type": "integer"}
+  }
+}`),
+	}}
+
+	target := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: "2.0.0"},
+		Values: map[string]interface{}{
+			"service": map[string]interface{}{"type": "ClusterIP", "port": 80},
+			"image":   map[string]interface{}{"tag": "2.0"},
+			"auth":    map[string]interface{}{},
+		},
+		Schema: []byte(`{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "service": {
+      "type": "object",
+      "properties": {
+        "type": {"enum": ["ClusterIP", "LoadBalancer"]},
+        "port": {"type": "integer"}
+      }
+    },
+    "image": {
+      "type": "object",
+      "required": ["tag", "repository"],
+      "properties": {"tag": {"type": "string"}, "repository": {"type": "string"}}
+    },
+    "auth": {"type": "object", "required": ["secretName"]},
+    "replicas": {"type": "integer"}
+  }
+}`),
+	}}
+
+	tests := []struct {
+		name       string
+		userValues map[string]interface{}
+		expected   map[string]ChangeKind
+	}{
+		{
+			name:       "No user values",
+			userValues: map[string]interface{}{},
+			expected: map[string]ChangeKind{
+				"auth.secretName":  ChangeRequired,
+				"image.repository": ChangeRequired,
+			},
+		},
+		{
+			name: "Changes affecting set keys",
+			userValues: map[string]interface{}{
+				"service": map[string]interface{}{"type": "NodePort", "port": "http"},
+				"image":   map[string]interface{}{"repository": "nginx"},
+			},
+			expected: map[string]ChangeKind{
+				"auth.secretName": ChangeRequired,
+				"service.port":    ChangeType,
+				"service.type":    ChangeEnum,
+			},
+		},
+		{
+			name: "Unchanged constraints",
+			userValues: map[string]interface{}{
+				"replicas": 3,
+				"image":    map[string]interface{}{"tag": "1.1", "repository": "nginx"},
+				"auth":     map[string]interface{}{"secretName": "auth"},
+			},
+			expected: map[string]ChangeKind{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Diff(base, target, tt.userValues)
+
+			got := make(map[string]ChangeKind)
+			for _, change := range changes {
+				got[change.Path.String()] = change.Kind
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Diff() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffNewSchema(t *testing.T) {
+	base := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: "1.0.0"},
+	}}
+	target := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: "2.0.0"},
+		Schema: []byte(`{
+  "type": "object",
+  "properties": {
+    "replicas": {"type": "integer"},
+    "mode": {"enum": ["a", "b"]}
+  }
+}`),
+	}}
+
+	changes := Diff(base, target, map[string]interface{}{"replicas": "3", "mode": "a"})
+
+	if len(changes) != 1 || changes[0].Path.String() != "replicas" || changes[0].Kind != ChangeType {
+		t.Errorf("Diff() = %v, want a single type change at replicas", changes)
+	}
+}
+
+func TestDiffInvalidSchema(t *testing.T) {
+	base := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: "1.0.0"},
+		Schema:   []byte(`{"type": "object", "required": ["replicas"]}`),
+	}}
+	target := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "app", Version: "2.0.0"},
+		Schema:   []byte(`{"type": "object",`),
+	}}
+
+	changes := Diff(base, target, map[string]interface{}{"replicas": 3})
+	if len(changes) != 1 || len(changes[0].Path) != 0 || changes[0].Kind != ChangeInvalid {
+		t.Fatalf("Diff() = %v, want a single invalid schema change", changes)
+	}
+	if !strings.Contains(changes[0].Message, "the schema of app 2.0.0 is invalid") {
+		t.Errorf("Message = %q, want it to name the invalid schema", changes[0].Message)
+	}
+}
@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Additional        `json:"additionalProperties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	Items                json.RawMessage    `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+
+	root *Schema
+}
+
+type Types []string
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid type: %s", data)
+	}
+	*t = list
+	return nil
+}
+
+func (t Types) Has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.Allowed = allowed
+		return nil
+	}
+
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+func Parse(data []byte) (*Schema, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	s.setRoot(&s)
+
+	return &s, nil
+}
+
+func (s *Schema) setRoot(root *Schema) {
+	if s == nil {
+		return
+	}
+	s.root = root
+
+	for _, children := range []map[string]*Schema{s.Properties, s.PatternProperties, s.Definitions, s.Defs} {
+		for _, child := range children {
+			child.setRoot(root)
+		}
+	}
+	if s.AdditionalProperties != nil {
+		s.AdditionalProperties.Schema.setRoot(root)
+	}
+}
+
+func (s *Schema) resolve() *Schema {
+	for depth := 0; s != nil && s.Ref != "" && depth < 32; depth++ {
+		name, ok := strings.CutPrefix(s.Ref, "#/definitions/")
+		definitions := s.root.Definitions
+		if !ok {
+			name, ok = strings.CutPrefix(s.Ref, "#/$defs/")
+			definitions = s.root.Defs
+		}
+		if !ok {
+			return s
+		}
+		next, exists := definitions[name]
+		if !exists {
+			return s
+		}
+		s = next
+	}
+	return s
+}
+
+func (s *Schema) ItemSchema() *Schema {
+	if s == nil || len(s.Items) == 0 || s.Items[0] != '{' {
+		return nil
+	}
+
+	var items Schema
+	if err := json.Unmarshal(s.Items, &items); err != nil {
+		return nil
+	}
+	items.setRoot(s.root)
+	return &items
+}
+
+func (s *Schema) isObject() bool {
+	return s.Type.Has("object") || len(s.Properties) > 0
+}
+
+func (s *Schema) isFreeForm() bool {
+	if !s.isObject() {
+		return false
+	}
+	if len(s.PatternProperties) > 0 {
+		return true
+	}
+	if s.AdditionalProperties != nil {
+		return s.AdditionalProperties.Allowed
+	}
+	return len(s.Properties) == 0
+}
+
+func (s *Schema) FreeForm() []values.Path {
+	if s == nil {
+		return nil
+	}
+	return s.freeForm(nil, 0)
+}
+
+func (s *Schema) freeForm(prefix values.Path, depth int) []values.Path {
+	s = s.resolve()
+	if s == nil || depth > 64 {
+		return nil
+	}
+
+	if len(prefix) > 0 && s.isFreeForm() {
+		return []values.Path{prefix}
+	}
+
+	var paths []values.Path
+	for k, child := range s.Properties {
+		paths = append(paths, child.freeForm(prefix.Child(k), depth+1)...)
+	}
+	return paths
+}
@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "Empty schema", data: "", wantNil: true},
+		{name: "Valid schema", data: `{"type": "object", "properties": {"a": {"type": ["string", "null"]}}}`},
+		{name: "Invalid JSON", data: `{"type": `, wantErr: true},
+		{name: "Invalid type", data: `{"type": 1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("Parse() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestFreeForm(t *testing.T) {
+	data := `{
+  "type": "object",
+  "additionalProperties": false,
+  "definitions": {
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  },
+  "properties": {
+    "podAnnotations": {"type": "object"},
+    "podLabels": {"$ref": "#/definitions/labels"},
+    "extraEnv": {"type": "object", "patternProperties": {"^[A-Z_]+$": {"type": "string"}}},
+    "image": {
+      "type": "object",
+      "properties": {
+        "tag": {"type": "string"},
+        "extra": {"type": "object", "additionalProperties": true}
+      }
+    },
+    "strict": {"type": "object", "additionalProperties": false},
+    "replicas": {"type": "integer"}
+  }
+}`
+
+	s, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.FreeForm()
+	values.SortPaths(got)
+
+	expected := []values.Path{
+		values.KeyPath("extraEnv"),
+		values.KeyPath("image", "extra"),
+		values.KeyPath("podAnnotations"),
+		values.KeyPath("podLabels"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("FreeForm() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+const contextDelimiter = "\x00"
+
+type Violation struct {
+	Path    values.Path
+	Message string
+}
+
+func Validate(c *chart.Chart, userValues map[string]interface{}) ([]Violation, error) {
+	coalesced, err := chartutil.CoalesceValues(c.Chart, userValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to coalesce values: %w", err)
+	}
+
+	return validateChart(nil, c.Chart, coalesced.AsMap())
+}
+
+func validateChart(prefix values.Path, c *helmchart.Chart, vals map[string]interface{}) ([]Violation, error) {
+	var violations []Violation
+
+	if len(c.Schema) > 0 {
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(c.Schema), gojsonschema.NewGoLoader(vals))
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate values against the schema of %s: %w", c.Name(), err)
+		}
+
+		for _, e := range result.Errors() {
+			path := append(append(values.Path{}, prefix...), contextPath(e.Context(), vals)...)
+			if property, ok := e.Details()["property"].(string); ok && e.Type() == "required" {
+				path = path.Child(property)
+			}
+			violations = append(violations, Violation{Path: path, Message: e.Description()})
+		}
+	}
+
+	for _, dependency := range c.Dependencies() {
+		key := chart.DependencyKey(c, dependency)
+		dependencyValues, ok := asMap(vals[key])
+		if !ok {
+			continue
+		}
+
+		dependencyViolations, err := validateChart(prefix.Child(key), dependency, dependencyValues)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, dependencyViolations...)
+	}
+
+	return violations, nil
+}
+
+func contextPath(context *gojsonschema.JsonContext, vals map[string]interface{}) values.Path {
+	segments := strings.Split(context.String(contextDelimiter), contextDelimiter)
+
+	var path values.Path
+	var current interface{} = vals
+	for _, segment := range segments[1:] {
+		switch typed := current.(type) {
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index >= len(typed) {
+				return path.Child(segment)
+			}
+			path = path.Item(index)
+			current = typed[index]
+		case map[string]interface{}, chartutil.Values:
+			m, _ := asMap(typed)
+			path = path.Child(segment)
+			current = m[segment]
+		default:
+			path = path.Child(segment)
+			current = nil
+		}
+	}
+
+	return path
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		return typed, true
+	case chartutil.Values:
+		return typed.AsMap(), true
+	default:
+		return nil, false
+	}
+}
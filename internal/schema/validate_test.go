@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+)
+
+func TestValidate(t *testing.T) {
+	subchart := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "grafana"},
+		Values:   map[string]interface{}{"replicas": 1},
+		Schema:   []byte(`{"type": "object", "properties": {"replicas": {"type": "integer", "minimum": 1}}}`),
+	}
+	parent := &helmchart.Chart{
+		Metadata: &helmchart.Metadata{
+			Name:         "stack",
+			Dependencies: []*helmchart.Dependency{{Name: "grafana"}},
+		},
+		Values: map[string]interface{}{
+			"image":    map[string]interface{}{"tag": "1.0"},
+			"extraEnv": []interface{}{map[string]interface{}{"name": "A"}},
+		},
+		Schema: []byte(`{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {"tag": {"type": "string"}, "repository": {"type": "string"}}
+    },
+    "extraEnv": {
+      "type": "array",
+      "items": {"type": "object", "properties": {"name": {"type": "string"}}}
+    }
+  }
+}`),
+	}
+	parent.SetDependencies(subchart)
+
+	userValues := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": 2},
+		"extraEnv": []interface{}{map[string]interface{}{"name": 1}},
+		"grafana":  map[string]interface{}{"replicas": 0},
+	}
+
+	violations, err := Validate(&chart.Chart{Chart: parent}, userValues)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, v := range violations {
+		got[v.Path.String()] = v.Message
+	}
+
+	expected := map[string]string{
+		"extraEnv[0].name": "Invalid type. Expected: string, given: integer",
+		"image.repository": "repository is required",
+		"image.tag":        "Invalid type. Expected: string, given: integer",
+		"grafana.replicas": "Must be greater than or equal to 1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Validate() = %v, want %v", got, expected)
+	}
+}
+
+func TestValidateWithoutSchema(t *testing.T) {
+	c := &chart.Chart{Chart: &helmchart.Chart{
+		Metadata: &helmchart.Metadata{Name: "plain"},
+		Values:   map[string]interface{}{"a": 1},
+	}}
+
+	violations, err := Validate(c, map[string]interface{}{"a": "anything"})
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Validate() = %v, want no violations", violations)
+	}
+}
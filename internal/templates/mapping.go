@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+type Consumer struct {
+	Template string
+	InBase   bool
+	InTarget bool
+	Changed  bool
+}
+
+type Mapping struct {
+	Path               values.Path
+	Consumers          []Consumer
+	ReferencedInBase   bool
+	ReferencedInTarget bool
+}
+
+func (m Mapping) ChangedConsumers() []Consumer {
+	var changed []Consumer
+	for _, consumer := range m.Consumers {
+		if consumer.Changed || consumer.InBase != consumer.InTarget {
+			changed = append(changed, consumer)
+		}
+	}
+	return changed
+}
+
+func Map(base, target *Set, paths []values.Path) []Mapping {
+	mappings := make([]Mapping, 0, len(paths))
+
+	for _, p := range paths {
+		consumers := make(map[string]*Consumer)
+		for name, t := range base.Templates {
+			if t.references(p) {
+				consumers[name] = &Consumer{Template: name, InBase: true}
+			}
+		}
+		for name, t := range target.Templates {
+			if !t.references(p) {
+				continue
+			}
+			consumer, ok := consumers[name]
+			if !ok {
+				consumer = &Consumer{Template: name}
+				consumers[name] = consumer
+			}
+			consumer.InTarget = true
+		}
+
+		mapping := Mapping{Path: p}
+		for name, consumer := range consumers {
+			if consumer.InBase && consumer.InTarget {
+				consumer.Changed = changed(base, target, name)
+			}
+			mapping.ReferencedInBase = mapping.ReferencedInBase || consumer.InBase
+			mapping.ReferencedInTarget = mapping.ReferencedInTarget || consumer.InTarget
+			mapping.Consumers = append(mapping.Consumers, *consumer)
+		}
+		sort.Slice(mapping.Consumers, func(i, j int) bool {
+			return mapping.Consumers[i].Template < mapping.Consumers[j].Template
+		})
+
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings
+}
+
+func (t *Template) references(p values.Path) bool {
+	for _, ref := range t.References {
+		if p.HasPrefix(ref) || ref.HasPrefix(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func changed(base, target *Set, name string) bool {
+	baseTemplate, targetTemplate := base.Templates[name], target.Templates[name]
+	if !bytes.Equal(baseTemplate.Data, targetTemplate.Data) {
+		return true
+	}
+
+	includes := make(map[string]bool)
+	for _, include := range baseTemplate.Includes {
+		includes[include] = true
+	}
+	for _, include := range targetTemplate.Includes {
+		includes[include] = true
+	}
+	for include := range includes {
+		if base.Defines[include] != target.Defines[include] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,345 @@
+package templates
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+const maxIncludeDepth = 32
+
+type Template struct {
+	Name       string
+	Data       []byte
+	References []values.Path
+	Includes   []string
+}
+
+type Set struct {
+	Templates map[string]*Template
+	Defines   map[string]string
+}
+
+type file struct {
+	name   string
+	data   []byte
+	prefix values.Path
+	tree   *parse.Tree
+}
+
+type scanner struct {
+	defines map[string]*parse.Tree
+}
+
+type scope struct {
+	root   bool
+	values values.Path
+	known  bool
+}
+
+type state struct {
+	prefix     values.Path
+	references map[string]values.Path
+	includes   map[string]bool
+	variables  map[string]scope
+	top        scope
+	stack      []string
+}
+
+func Scan(c *chart.Chart) (*Set, error) {
+	files, defines, err := parseChart(c.Chart, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scanner{defines: defines}
+	set := &Set{
+		Templates: make(map[string]*Template, len(files)),
+		Defines:   make(map[string]string, len(defines)),
+	}
+	for name, tree := range defines {
+		set.Defines[name] = tree.Root.String()
+	}
+
+	for _, f := range files {
+		st := &state{
+			prefix:     f.prefix,
+			references: make(map[string]values.Path),
+			includes:   make(map[string]bool),
+			variables:  make(map[string]scope),
+			top:        scope{root: true},
+		}
+		s.walk(st, f.tree.Root, st.top)
+
+		t := &Template{Name: f.name, Data: f.data}
+		for _, ref := range st.references {
+			t.References = append(t.References, ref)
+		}
+		sort.Slice(t.References, func(i, j int) bool {
+			return t.References[i].String() < t.References[j].String()
+		})
+		for name := range st.includes {
+			t.Includes = append(t.Includes, name)
+		}
+		sort.Strings(t.Includes)
+
+		set.Templates[f.name] = t
+	}
+
+	return set, nil
+}
+
+func parseChart(c *helmchart.Chart, dir string, prefix values.Path) ([]file, map[string]*parse.Tree, error) {
+	var files []file
+	defines := make(map[string]*parse.Tree)
+
+	for _, t := range c.Templates {
+		name := path.Join(dir, t.Name)
+
+		tree := parse.New(name)
+		tree.Mode = parse.SkipFuncCheck
+		treeSet := make(map[string]*parse.Tree)
+		if _, err := tree.Parse(string(t.Data), "", "", treeSet); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+
+		for defineName, define := range treeSet {
+			if defineName != name {
+				defines[defineName] = define
+			}
+		}
+
+		if !strings.HasPrefix(path.Base(t.Name), "_") {
+			files = append(files, file{name: name, data: t.Data, prefix: prefix, tree: tree})
+		}
+	}
+
+	for _, dependency := range c.Dependencies() {
+		key := chart.DependencyKey(c, dependency)
+		dependencyFiles, dependencyDefines, err := parseChart(dependency, path.Join(dir, "charts", dependency.Name()), prefix.Child(key))
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, dependencyFiles...)
+		for name, define := range dependencyDefines {
+			if _, exists := defines[name]; !exists {
+				defines[name] = define
+			}
+		}
+	}
+
+	return files, defines, nil
+}
+
+func (s *scanner) walk(st *state, node parse.Node, dot scope) {
+	switch n := node.(type) {
+	case nil:
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			s.walk(st, child, dot)
+		}
+	case *parse.ActionNode:
+		s.pipe(st, n.Pipe, dot)
+	case *parse.IfNode:
+		s.pipe(st, n.Pipe, dot)
+		s.walk(st, n.List, dot)
+		s.walk(st, n.ElseList, dot)
+	case *parse.WithNode:
+		inner := s.pipe(st, n.Pipe, dot)
+		s.walk(st, n.List, inner)
+		s.walk(st, n.ElseList, dot)
+	case *parse.RangeNode:
+		s.pipe(st, n.Pipe, dot)
+		s.walk(st, n.List, scope{})
+		s.walk(st, n.ElseList, dot)
+	case *parse.TemplateNode:
+		arg := scope{}
+		if n.Pipe != nil {
+			arg = s.pipe(st, n.Pipe, dot)
+		}
+		s.include(st, n.Name, arg)
+	}
+}
+
+func (s *scanner) pipe(st *state, pipe *parse.PipeNode, dot scope) scope {
+	if pipe == nil {
+		return scope{}
+	}
+
+	var result scope
+	for _, cmd := range pipe.Cmds {
+		result = s.command(st, cmd, dot)
+	}
+	if len(pipe.Cmds) > 1 {
+		result = scope{}
+	}
+
+	for _, variable := range pipe.Decl {
+		if len(variable.Ident) > 0 {
+			st.variables[variable.Ident[0]] = result
+		}
+	}
+
+	return result
+}
+
+func (s *scanner) command(st *state, cmd *parse.CommandNode, dot scope) scope {
+	if len(cmd.Args) == 0 {
+		return scope{}
+	}
+
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		switch ident.Ident {
+		case "index":
+			if len(cmd.Args) > 1 {
+				return s.index(st, cmd.Args[1], cmd.Args[2:], dot)
+			}
+		case "include":
+			if len(cmd.Args) > 1 {
+				if name, ok := cmd.Args[1].(*parse.StringNode); ok {
+					arg := scope{}
+					if len(cmd.Args) > 2 {
+						arg = s.arg(st, cmd.Args[2], dot)
+					}
+					for _, extra := range cmd.Args[3:] {
+						s.arg(st, extra, dot)
+					}
+					s.include(st, name.Text, arg)
+					return scope{}
+				}
+			}
+		}
+	}
+
+	var result scope
+	for _, arg := range cmd.Args {
+		result = s.arg(st, arg, dot)
+	}
+	if len(cmd.Args) > 1 {
+		result = scope{}
+	}
+	return result
+}
+
+func (s *scanner) arg(st *state, node parse.Node, dot scope) scope {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return st.reference(resolve(dot, n.Ident))
+	case *parse.VariableNode:
+		return st.reference(resolve(st.variable(n.Ident[0]), n.Ident[1:]))
+	case *parse.ChainNode:
+		var inner scope
+		if pipe, ok := n.Node.(*parse.PipeNode); ok {
+			inner = s.pipe(st, pipe, dot)
+		} else {
+			inner = s.arg(st, n.Node, dot)
+		}
+		return st.reference(resolve(inner, n.Field))
+	case *parse.PipeNode:
+		return s.pipe(st, n, dot)
+	}
+	return scope{}
+}
+
+func (s *scanner) index(st *state, target parse.Node, keys []parse.Node, dot scope) scope {
+	var base scope
+	switch n := target.(type) {
+	case *parse.DotNode:
+		base = dot
+	case *parse.FieldNode:
+		base = resolve(dot, n.Ident)
+	case *parse.VariableNode:
+		base = resolve(st.variable(n.Ident[0]), n.Ident[1:])
+	default:
+		base = s.arg(st, target, dot)
+	}
+
+	for _, key := range keys {
+		str, ok := key.(*parse.StringNode)
+		if !ok {
+			s.arg(st, key, dot)
+			st.reference(base)
+			return scope{}
+		}
+		base = resolve(base, []string{str.Text})
+	}
+
+	return st.reference(base)
+}
+
+func (s *scanner) include(st *state, name string, arg scope) {
+	st.includes[name] = true
+
+	define, ok := s.defines[name]
+	if !ok || len(st.stack) >= maxIncludeDepth {
+		return
+	}
+	for _, caller := range st.stack {
+		if caller == name {
+			return
+		}
+	}
+
+	variables, top := st.variables, st.top
+	st.variables, st.top = make(map[string]scope), arg
+	st.stack = append(st.stack, name)
+	s.walk(st, define.Root, arg)
+	st.stack = st.stack[:len(st.stack)-1]
+	st.variables, st.top = variables, top
+}
+
+func (st *state) variable(name string) scope {
+	if name == "$" {
+		return st.top
+	}
+	return st.variables[name]
+}
+
+func (st *state) reference(sc scope) scope {
+	if !sc.known {
+		return sc
+	}
+
+	ref := append(values.Path{}, st.prefix...)
+	if len(sc.values) > 0 && sc.values[0].Key == "global" && !sc.values[0].IsIndex {
+		ref = values.Path{}
+	}
+	ref = append(ref, sc.values...)
+
+	st.references[ref.String()] = ref
+	return sc
+}
+
+func resolve(sc scope, fields []string) scope {
+	if len(fields) == 0 {
+		return sc
+	}
+
+	if sc.root {
+		if fields[0] != "Values" {
+			return scope{}
+		}
+		return resolve(scope{known: true, values: values.Path{}}, fields[1:])
+	}
+	if !sc.known {
+		return scope{}
+	}
+
+	result := append(values.Path{}, sc.values...)
+	for _, field := range fields {
+		result = result.Child(field)
+	}
+	return scope{known: true, values: result}
+}
@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"reflect"
+	"testing"
+
+	helmchart "helm.sh/helm/v3/pkg/chart"
+
+	"github.com/cstanislawski/helm-valgrade/internal/chart"
+	"github.com/cstanislawski/helm-valgrade/internal/values"
+)
+
+func testChart(templates map[string]string) *helmchart.Chart {
+	c := &helmchart.Chart{Metadata: &helmchart.Metadata{Name: "app"}}
+	for name, data := range templates {
+		c.Templates = append(c.Templates, &helmchart.File{Name: "templates/" + name, Data: []byte(data)})
+	}
+	return c
+}
+
+func TestScan(t *testing.T) {
+	c := testChart(map[string]string{
+		"_helpers.tpl": `{{- define "app.image" -}}{{ .Values.image.repository }}:{{ .Values.image.tag | default .Chart.AppVersion }}{{- end -}}
+{{- define "app.labels" -}}{{ toYaml .labels }}{{ $.extra }}{{- end -}}
+{{- define "app.recursive" -}}{{ include "app.recursive" . }}{{- end -}}`,
+		"deployment.yaml": `spec:
+  replicas: {{ .Values.replicas }}
+  image: {{ include "app.image" . }}
+  {{- with .Values.resources }}
+  resources: {{ toYaml .limits | nindent 4 }}
+  {{- end }}
+  {{- $svc := .Values.service }}
+  port: {{ $svc.port }}
+  host: {{ $.Values.ingress.host }}
+  annotations: {{ index .Values "podAnnotations" "prometheus.io/scrape" }}
+  {{- range .Values.extraEnv }}
+  - {{ .name }}
+  {{- end }}
+  {{- include "app.recursive" . }}
+  labels: {{ include "app.labels" .Values.common }}`,
+		"configmap.yaml": `data: {{ tpl (.Values.config | toYaml) . }}`,
+	})
+	subchart := testChart(map[string]string{
+		"secret.yaml": `data: {{ .Values.password }}{{ .Values.global.domain }}`,
+	})
+	subchart.Metadata.Name = "db"
+	c.Metadata.Dependencies = []*helmchart.Dependency{{Name: "db", Alias: "database"}}
+	c.SetDependencies(subchart)
+
+	set, err := Scan(&chart.Chart{Chart: c})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	expected := map[string][]string{
+		"templates/configmap.yaml": {"config"},
+		"templates/deployment.yaml": {
+			"common",
+			"common.extra",
+			"common.labels",
+			"extraEnv",
+			"image.repository",
+			"image.tag",
+			`ingress.host`,
+			`podAnnotations."prometheus.io/scrape"`,
+			"replicas",
+			"resources",
+			"resources.limits",
+			"service",
+			"service.port",
+		},
+		"charts/db/templates/secret.yaml": {"database.password", "global.domain"},
+	}
+
+	got := make(map[string][]string)
+	for name, tmpl := range set.Templates {
+		for _, ref := range tmpl.References {
+			got[name] = append(got[name], ref.String())
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Scan() references = %v, want %v", got, expected)
+	}
+
+	includes := set.Templates["templates/deployment.yaml"].Includes
+	if !reflect.DeepEqual(includes, []string{"app.image", "app.labels", "app.recursive"}) {
+		t.Errorf("Scan() includes = %v", includes)
+	}
+}
+
+func TestScanInvalidTemplate(t *testing.T) {
+	c := testChart(map[string]string{"broken.yaml": "{{ .Values.a "})
+	if _, err := Scan(&chart.Chart{Chart: c}); err == nil {
+		t.Error("Scan() expected an error for an invalid template")
+	}
+}
+
+func TestMap(t *testing.T) {
+	base, err := Scan(&chart.Chart{Chart: testChart(map[string]string{
+		"_helpers.tpl":    `{{- define "app.image" -}}{{ .Values.image.repository }}:{{ .Values.image.tag }}{{- end -}}`,
+		"deployment.yaml": `image: {{ include "app.image" . }}`,
+		"service.yaml":    `port: {{ .Values.service.port }}`,
+		"legacy.yaml":     `{{ .Values.legacy.enabled }}`,
+	})})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	target, err := Scan(&chart.Chart{Chart: testChart(map[string]string{
+		"_helpers.tpl":    `{{- define "app.image" -}}{{ .Values.image.registry }}/{{ .Values.image.repository }}:{{ .Values.image.tag }}{{- end -}}`,
+		"deployment.yaml": `image: {{ include "app.image" . }}`,
+		"service.yaml":    `port: {{ .Values.service.port }}`,
+	})})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	paths := []values.Path{
+		values.KeyPath("image", "tag"),
+		values.KeyPath("legacy", "enabled"),
+		values.KeyPath("service", "port"),
+		values.KeyPath("typo"),
+	}
+
+	mappings := Map(base, target, paths)
+
+	expected := []Mapping{
+		{
+			Path:               paths[0],
+			Consumers:          []Consumer{{Template: "templates/deployment.yaml", InBase: true, InTarget: true, Changed: true}},
+			ReferencedInBase:   true,
+			ReferencedInTarget: true,
+		},
+		{
+			Path:             paths[1],
+			Consumers:        []Consumer{{Template: "templates/legacy.yaml", InBase: true}},
+			ReferencedInBase: true,
+		},
+		{
+			Path:               paths[2],
+			Consumers:          []Consumer{{Template: "templates/service.yaml", InBase: true, InTarget: true}},
+			ReferencedInBase:   true,
+			ReferencedInTarget: true,
+		},
+		{
+			Path: paths[3],
+		},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("Map() = %+v, want %+v", mappings, expected)
+	}
+
+	if changed := mappings[2].ChangedConsumers(); len(changed) != 0 {
+		t.Errorf("ChangedConsumers() = %v, want none", changed)
+	}
+	if changed := mappings[1].ChangedConsumers(); len(changed) != 1 {
+		t.Errorf("ChangedConsumers() = %v, want the removed template", changed)
+	}
+}
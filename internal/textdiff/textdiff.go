@@ -0,0 +1,251 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op byte
+
+const (
+	OpEqual  Op = ' '
+	OpDelete Op = '-'
+	OpInsert Op = '+'
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+func Unified(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	if context < 0 {
+		context = 0
+	}
+
+	lines := Lines(splitLines(a), splitLines(b))
+
+	var hunks [][2]int
+	for i, line := range lines {
+		if line.Op == OpEqual {
+			continue
+		}
+		start, end := max(0, i-context), min(len(lines), i+context+1)
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = end
+			continue
+		}
+		hunks = append(hunks, [2]int{start, end})
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		aStart, bStart := count(lines[:hunk[0]])
+		aLen, bLen := count(lines[hunk[0]:hunk[1]])
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, line := range lines[hunk[0]:hunk[1]] {
+			sb.WriteByte(byte(line.Op))
+			sb.WriteString(line.Text)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
+
+func Colorize(unified string) string {
+	lines := strings.SplitAfter(unified, "\n")
+
+	var sb strings.Builder
+	inHunk := false
+	for _, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		color := ""
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			color = colorCyan
+			inHunk = true
+		case !inHunk:
+			color = colorBold
+		case strings.HasPrefix(text, string(OpDelete)):
+			color = colorRed
+		case strings.HasPrefix(text, string(OpInsert)):
+			color = colorGreen
+		}
+
+		if color == "" || text == "" {
+			sb.WriteString(line)
+			continue
+		}
+		sb.WriteString(color)
+		sb.WriteString(text)
+		sb.WriteString(colorReset)
+		sb.WriteString(line[len(text):])
+	}
+	return sb.String()
+}
+
+func Lines(a, b []string) []Line {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	return diffLines(make([]Line, 0, max(len(a), len(b))), a, b)
+}
+
+func diffLines(lines []Line, a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		lines = append(lines, Line{Op: OpEqual, Text: a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	x, y := -1, -1
+	if len(a) > 0 && len(b) > 0 {
+		x, y = bisect(a, b)
+	}
+	if x < 0 {
+		for _, text := range a {
+			lines = append(lines, Line{Op: OpDelete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, Line{Op: OpInsert, Text: text})
+		}
+	} else {
+		lines = diffLines(lines, a[:x], b[:y])
+		lines = diffLines(lines, a[x:], b[y:])
+	}
+
+	for _, text := range common {
+		lines = append(lines, Line{Op: OpEqual, Text: text})
+	}
+	return lines
+}
+
+func bisect(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	reverse := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		reverse[i] = -1
+	}
+	forward[offset+1] = 0
+	reverse[offset+1] = 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	var kStart, kEnd, rStart, rEnd int
+	for d := 0; d < maxD; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				kEnd += 2
+			case y > m:
+				kStart += 2
+			case odd:
+				r := offset + delta - k
+				if r >= 0 && r < len(reverse) && reverse[r] != -1 && x >= n-reverse[r] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && reverse[offset+k-1] < reverse[offset+k+1]) {
+				x = reverse[offset+k+1]
+			} else {
+				x = reverse[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			reverse[offset+k] = x
+
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				f := offset + delta - k
+				if f >= 0 && f < len(forward) && forward[f] != -1 && forward[f] >= n-x {
+					return forward[f], forward[f] - (f - offset)
+				}
+			}
+		}
+	}
+
+	return -1, -1
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func count(lines []Line) (int, int) {
+	var a, b int
+	for _, line := range lines {
+		if line.Op != OpInsert {
+			a++
+		}
+		if line.Op != OpDelete {
+			b++
+		}
+	}
+	return a, b
+}
+
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
@@ -0,0 +1,160 @@
+package textdiff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		context  int
+		expected string
+	}{
+		{
+			name:     "Equal",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			context:  3,
+			expected: "",
+		},
+		{
+			name:     "Modified line",
+			a:        "a\nb\nc\n",
+			b:        "a\nx\nc\n",
+			context:  3,
+			expected: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name:     "Added file",
+			a:        "",
+			b:        "a\nb\n",
+			context:  3,
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "Removed file",
+			a:        "a\n",
+			b:        "",
+			context:  3,
+			expected: "--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name:     "Separate hunks",
+			a:        "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:        "x\n2\n3\n4\n5\n6\n7\ny\n",
+			context:  1,
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+y\n",
+		},
+		{
+			name:     "Merged hunks",
+			a:        "1\n2\n3\n4\n",
+			b:        "x\n2\n3\ny\n",
+			context:  1,
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+		{
+			name:     "No context",
+			a:        "a\nb\nc\n",
+			b:        "a\nb\nc\nd\n",
+			context:  0,
+			expected: "--- old\n+++ new\n@@ -3,0 +4 @@\n+d\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.a, tt.b, tt.context); got != tt.expected {
+				t.Errorf("Unified() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestColorize(t *testing.T) {
+	unified := Unified("old", "new", "a\n-- b\nc\n", "a\nx\nc\n", 1)
+
+	expected := "\x1b[1m--- old\x1b[0m\n" +
+		"\x1b[1m+++ new\x1b[0m\n" +
+		"\x1b[36m@@ -1,3 +1,3 @@\x1b[0m\n" +
+		" a\n" +
+		"\x1b[31m--- b\x1b[0m\n" +
+		"\x1b[32m+x\x1b[0m\n" +
+		" c\n"
+	if got := Colorize(unified); got != expected {
+		t.Errorf("Colorize() = %q, want %q", got, expected)
+	}
+}
+
+func TestLines(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 200; i++ {
+		a, b := random(rng.Intn(30)), random(rng.Intn(30))
+		var gotA, gotB []string
+		edits := 0
+		for _, line := range Lines(a, b) {
+			if line.Op != OpEqual {
+				edits++
+			}
+			if line.Op != OpInsert {
+				gotA = append(gotA, line.Text)
+			}
+			if line.Op != OpDelete {
+				gotB = append(gotB, line.Text)
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("Lines(%v, %v) does not reproduce both inputs", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Lines(%v, %v) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+
+	large := make([]string, 50000)
+	for i := range large {
+		large[i] = fmt.Sprintf("line %d", i)
+	}
+	changed := append([]string(nil), large...)
+	for i := 0; i < len(changed); i += 100 {
+		changed[i] = "changed"
+	}
+	edits := 0
+	for _, line := range Lines(large, changed) {
+		if line.Op != OpEqual {
+			edits++
+		}
+	}
+	if edits != 1000 {
+		t.Errorf("Lines() of a large input has %d edits, want 1000", edits)
+	}
+}
+
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
@@ -0,0 +1,189 @@
+package values
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+type File struct {
+	Path      string
+	Format    Format
+	Documents []*yaml.Node
+	Document  int
+	Node      *yaml.Node
+	Raw       []byte
+}
+
+func LoadFile(filename string, document int) (*File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return Parse(filename, data, document)
+}
+
+func Parse(filename string, data []byte, document int) (*File, error) {
+	f := &File{Path: filename, Format: detectFormat(data), Document: document, Raw: data}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal values: %w", err)
+		}
+		f.Documents = append(f.Documents, &node)
+	}
+
+	if len(f.Documents) == 0 {
+		f.Documents = append(f.Documents, &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}})
+	}
+
+	if err := f.Select(document); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *File) Select(document int) error {
+	if document < 0 || document >= len(f.Documents) {
+		return fmt.Errorf("document %d not found, file has %d document(s)", document, len(f.Documents))
+	}
+	f.Document = document
+	f.Node = f.Documents[document]
+	return nil
+}
+
+func (f *File) Write(filename string) error {
+	out, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	return f.Encode(out)
+}
+
+func (f *File) Encode(w io.Writer) error {
+	if f.Format == FormatJSON {
+		return EncodeJSON(w, f.Node)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	for _, doc := range f.Documents {
+		if err := encoder.Encode(doc); err != nil {
+			return fmt.Errorf("failed to encode values: %w", err)
+		}
+	}
+
+	return encoder.Close()
+}
+
+func EncodeJSON(w io.Writer, node *yaml.Node) error {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, node, ""); err != nil {
+		return fmt.Errorf("failed to encode values: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func detectFormat(data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+func writeJSON(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		return writeJSON(buf, node.Content[0], indent)
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias, indent)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i := 0; i < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(indent + "  ")
+			if err := writeJSONValue(buf, node.Content[i].Value); err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			if err := writeJSON(buf, node.Content[i+1], indent+"  "); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.WriteString(indent + "  ")
+			if err := writeJSON(buf, item, indent+"  "); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + indent + "]")
+	case yaml.ScalarNode:
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		return writeJSONValue(buf, v)
+	default:
+		return fmt.Errorf("unsupported node kind %d", node.Kind)
+	}
+
+	return nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) error {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	buf.Write(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,144 @@
+package values
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseMultiDocument(t *testing.T) {
+	content := "# first\nname: first\n---\n# second\nname: second\nreplicas: 1\n"
+
+	tests := []struct {
+		name     string
+		document int
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Select first document",
+			document: 0,
+			want:     "first",
+		},
+		{
+			name:     "Select second document",
+			document: 1,
+			want:     "second",
+		},
+		{
+			name:     "Select missing document",
+			document: 2,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse("values.yaml", []byte(content), tt.document)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := GetValue(f.Node, "name")
+			if err != nil {
+				t.Fatalf("GetValue() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeMultiDocument(t *testing.T) {
+	content := "# first\nname: first\n---\n# second\nname: second\nreplicas: 1\n"
+
+	f, err := Parse("values.yaml", []byte(content), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetValue(f.Node, "3", "replicas"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	expected := "# first\nname: first\n---\n# second\nname: second\nreplicas: 3\n"
+	if buf.String() != expected {
+		t.Errorf("Encode() = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	content := `{"name": "app", "replicas": 1, "image": {"tag": "1.0", "pullPolicy": "IfNotPresent"}, "args": ["--a", "<b>"], "empty": {}, "ratio": 0.5, "enabled": true, "extra": null}`
+
+	f, err := Parse("values.json", []byte(content), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Format != FormatJSON {
+		t.Fatalf("Format = %v, want %v", f.Format, FormatJSON)
+	}
+
+	if err := SetValue(f.Node, "2.0", "image", "tag"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(f.Node, "8080", "service", "port"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	expected := `{
+  "name": "app",
+  "replicas": 1,
+  "image": {
+    "tag": "2.0",
+    "pullPolicy": "IfNotPresent"
+  },
+  "args": [
+    "--a",
+    "<b>"
+  ],
+  "empty": {},
+  "ratio": 0.5,
+  "enabled": true,
+  "extra": null,
+  "service": {
+    "port": 8080
+  }
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Encode() = %s, want %s", buf.String(), expected)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	f, err := Parse("values.yaml", []byte(""), 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if err := SetValue(f.Node, "value", "key"); err != nil {
+		t.Fatalf("SetValue() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	if buf.String() != "key: value\n" {
+		t.Errorf("Encode() = %q, want %q", buf.String(), "key: value\n")
+	}
+}
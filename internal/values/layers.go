@@ -0,0 +1,118 @@
+package values
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func LoadFiles(paths []string, document int) ([]*File, error) {
+	files := make([]*File, 0, len(paths))
+	loaded := make(map[string]*File)
+	for _, path := range paths {
+		filename, index, explicit := SplitDocument(path)
+		parsed, ok := loaded[filename]
+		if !ok {
+			var err error
+			parsed, err = LoadFile(filename, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s: %w", filename, err)
+			}
+			loaded[filename] = parsed
+		}
+
+		f := *parsed
+		if !explicit && len(f.Documents) > 1 {
+			index = document
+		}
+		if err := f.Select(index); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", filename, err)
+		}
+		files = append(files, &f)
+	}
+
+	return files, nil
+}
+
+func Distinct(files []*File) []*File {
+	seen := make(map[string]bool, len(files))
+	distinct := make([]*File, 0, len(files))
+	for _, f := range files {
+		if seen[f.Path] {
+			continue
+		}
+		seen[f.Path] = true
+		distinct = append(distinct, f)
+	}
+
+	return distinct
+}
+
+func SplitDocument(path string) (string, int, bool) {
+	i := strings.LastIndex(path, "#")
+	if i < 0 {
+		return path, 0, false
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path, 0, false
+	}
+
+	index, err := strconv.Atoi(path[i+1:])
+	if err != nil || index < 0 {
+		return path, 0, false
+	}
+	return path[:i], index, true
+}
+
+func Merge(files []*File) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	for _, f := range files {
+		current := make(map[string]interface{})
+		if err := f.Node.Decode(&current); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", f.Path, err)
+		}
+		merged = MergeMaps(merged, current)
+	}
+
+	return merged, nil
+}
+
+func MergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(dst))
+	for k, v := range dst {
+		out[k] = v
+	}
+
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]interface{}); ok {
+			if dstMap, ok := out[k].(map[string]interface{}); ok {
+				out[k] = MergeMaps(dstMap, srcMap)
+				continue
+			}
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+func Owner(files []*File, path Path) *File {
+	for i := len(files) - 1; i >= 0; i-- {
+		if _, err := Lookup(files[i].Node, path); err == nil {
+			return files[i]
+		}
+	}
+
+	return nil
+}
+
+func Find(files []*File, path string) *File {
+	for _, f := range files {
+		if f.Path == path {
+			return f
+		}
+	}
+
+	return nil
+}
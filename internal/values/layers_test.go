@@ -0,0 +1,205 @@
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func writeLayers(t *testing.T, contents ...string) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	paths := make([]string, 0, len(contents))
+	for i, content := range contents {
+		path := filepath.Join(dir, fmt.Sprintf("values-%d.yaml", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+func TestLoadFilesDocuments(t *testing.T) {
+	paths := writeLayers(t, "replicas: 1\n---\nreplicas: 2\n---\nreplicas: 3\n", "image:\n  tag: \"1.0\"\n")
+
+	tests := []struct {
+		name     string
+		paths    []string
+		document int
+		expected []int
+		wantErr  bool
+	}{
+		{name: "document applies to multi-document files only", paths: paths, document: 1, expected: []int{1, 0}},
+		{name: "document per file", paths: []string{paths[0] + "#2", paths[1]}, document: 1, expected: []int{2, 0}},
+		{name: "document per file overrides the default", paths: []string{paths[0] + "#0", paths[1]}, document: 1, expected: []int{0, 0}},
+		{name: "explicit document of a single-document file", paths: []string{paths[0], paths[1] + "#1"}, wantErr: true},
+		{name: "document out of range", paths: paths, document: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := LoadFiles(tt.paths, tt.document)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, f := range files {
+				if f.Document != tt.expected[i] || f.Path != paths[i] {
+					t.Errorf("files[%d] = %s#%d, want %s#%d", i, f.Path, f.Document, paths[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFilesSharesDocuments(t *testing.T) {
+	paths := writeLayers(t, "a: 1\n---\nb: 2\n")
+
+	files, err := LoadFiles([]string{paths[0] + "#0", paths[0] + "#1"}, 0)
+	if err != nil {
+		t.Fatalf("LoadFiles() error = %v", err)
+	}
+	if err := SetNode(files[0].Node, KeyPath("a"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "10"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetNode(files[1].Node, KeyPath("b"), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "20"}); err != nil {
+		t.Fatal(err)
+	}
+
+	distinct := Distinct(files)
+	if len(distinct) != 1 {
+		t.Fatalf("Distinct() returned %d files, want 1", len(distinct))
+	}
+
+	var buf bytes.Buffer
+	if err := distinct[0].Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a: 10\n---\nb: 20\n"; buf.String() != expected {
+		t.Errorf("Encode() = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	paths := writeLayers(t,
+		"replicas: 1\nimage:\n  repository: nginx\n  tag: \"1.0\"\n",
+		"replicas: 3\nimage:\n  tag: \"2.0\"\n",
+		"",
+	)
+
+	files, err := LoadFiles(paths, 0)
+	if err != nil {
+		t.Fatalf("LoadFiles() error = %v", err)
+	}
+
+	got, err := Merge(files)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"replicas": 3,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "2.0",
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Merge() = %v, want %v", got, expected)
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst      map[string]interface{}
+		src      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Override scalar",
+			dst:      map[string]interface{}{"a": 1},
+			src:      map[string]interface{}{"a": 2},
+			expected: map[string]interface{}{"a": 2},
+		},
+		{
+			name:     "Merge nested maps",
+			dst:      map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
+			src:      map[string]interface{}{"a": map[string]interface{}{"c": 3}},
+			expected: map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 3}},
+		},
+		{
+			name:     "Replace map with scalar",
+			dst:      map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			src:      map[string]interface{}{"a": "value"},
+			expected: map[string]interface{}{"a": "value"},
+		},
+		{
+			name:     "Replace list",
+			dst:      map[string]interface{}{"a": []interface{}{1, 2}},
+			src:      map[string]interface{}{"a": []interface{}{3}},
+			expected: map[string]interface{}{"a": []interface{}{3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeMaps(tt.dst, tt.src)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("MergeMaps() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOwner(t *testing.T) {
+	paths := writeLayers(t,
+		"replicas: 1\nimage:\n  repository: nginx\n",
+		"replicas: 3\n",
+	)
+
+	files, err := LoadFiles(paths, 0)
+	if err != nil {
+		t.Fatalf("LoadFiles() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected *File
+	}{
+		{
+			name:     "Key in both layers",
+			keys:     []string{"replicas"},
+			expected: files[1],
+		},
+		{
+			name:     "Key in first layer",
+			keys:     []string{"image", "repository"},
+			expected: files[0],
+		},
+		{
+			name:     "Key in no layer",
+			keys:     []string{"image", "tag"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Owner(files, KeyPath(tt.keys...)); got != tt.expected {
+				t.Errorf("Owner() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package values
+
+import (
+	"fmt"
+	"os"
+
+	"helm.sh/helm/v3/pkg/strvals"
+)
+
+type Overrides struct {
+	Values       []string
+	StringValues []string
+	JSONValues   []string
+	FileValues   []string
+}
+
+func (o *Overrides) Parse() (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for _, value := range o.JSONValues {
+		if err := strvals.ParseJSON(value, result); err != nil {
+			return nil, fmt.Errorf("failed to parse --set-json data %s: %w", value, err)
+		}
+	}
+
+	for _, value := range o.Values {
+		if err := strvals.ParseInto(value, result); err != nil {
+			return nil, fmt.Errorf("failed to parse --set data: %w", err)
+		}
+	}
+
+	for _, value := range o.StringValues {
+		if err := strvals.ParseIntoString(value, result); err != nil {
+			return nil, fmt.Errorf("failed to parse --set-string data: %w", err)
+		}
+	}
+
+	for _, value := range o.FileValues {
+		reader := func(rs []rune) (interface{}, error) {
+			data, err := os.ReadFile(string(rs))
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		}
+		if err := strvals.ParseIntoFile(value, result, reader); err != nil {
+			return nil, fmt.Errorf("failed to parse --set-file data: %w", err)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package values
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOverridesParse(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.txt")
+	if err := os.WriteFile(configFile, []byte("line1\nline2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides Overrides
+		expected  map[string]interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "Set typed values",
+			overrides: Overrides{Values: []string{"replicas=3,image.tag=v1", "enabled=true"}},
+			expected: map[string]interface{}{
+				"replicas": int64(3),
+				"image":    map[string]interface{}{"tag": "v1"},
+				"enabled":  true,
+			},
+		},
+		{
+			name:      "Set string values",
+			overrides: Overrides{StringValues: []string{"replicas=3"}},
+			expected:  map[string]interface{}{"replicas": "3"},
+		},
+		{
+			name:      "Set JSON values",
+			overrides: Overrides{JSONValues: []string{`resources={"limits":{"cpu":"1"}}`}},
+			expected: map[string]interface{}{
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}},
+			},
+		},
+		{
+			name:      "Set file values",
+			overrides: Overrides{FileValues: []string{"config=" + configFile}},
+			expected:  map[string]interface{}{"config": "line1\nline2\n"},
+		},
+		{
+			name:      "Set takes precedence over set-json",
+			overrides: Overrides{JSONValues: []string{`a=1`}, Values: []string{"a=2"}},
+			expected:  map[string]interface{}{"a": int64(2)},
+		},
+		{
+			name:      "Invalid set value",
+			overrides: Overrides{Values: []string{"a[=1"}},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.overrides.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.expected)
+			}
+		})
+	}
+}
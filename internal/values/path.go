@@ -0,0 +1,190 @@
+package values
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Segment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+type Path []Segment
+
+func KeyPath(keys ...string) Path {
+	path := make(Path, 0, len(keys))
+	for _, key := range keys {
+		path = append(path, Segment{Key: key})
+	}
+	return path
+}
+
+func ParsePath(s string) (Path, error) {
+	var path Path
+	if s == "" {
+		return path, nil
+	}
+
+	i := 0
+	expectKey := true
+	for i < len(s) {
+		switch {
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated index at position %d", s, i)
+			}
+			index, err := strconv.Atoi(s[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %q: invalid index %q", s, s[i+1:i+end])
+			}
+			path = append(path, Segment{Index: index, IsIndex: true})
+			i += end + 1
+			expectKey = false
+		case s[i] == '.':
+			if expectKey {
+				return nil, fmt.Errorf("invalid path %q: empty key at position %d", s, i)
+			}
+			i++
+			expectKey = true
+			if i == len(s) {
+				return nil, fmt.Errorf("invalid path %q: trailing separator", s)
+			}
+		case !expectKey:
+			return nil, fmt.Errorf("invalid path %q: expected separator at position %d", s, i)
+		case s[i] == '"':
+			quoted, err := strconv.QuotedPrefix(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: unterminated quoted key at position %d", s, i)
+			}
+			key, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", s, err)
+			}
+			path = append(path, Segment{Key: key})
+			i += len(quoted)
+			expectKey = false
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			path = append(path, Segment{Key: s[i : i+end]})
+			i += end
+			expectKey = false
+		}
+	}
+
+	return path, nil
+}
+
+func ParsePaths(paths []string) ([]Path, error) {
+	parsed := make([]Path, 0, len(paths))
+	for _, p := range paths {
+		path, err := ParsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, path)
+	}
+	return parsed, nil
+}
+
+func (p Path) Child(key string) Path {
+	return p.append(Segment{Key: key})
+}
+
+func (p Path) Item(index int) Path {
+	return p.append(Segment{Index: index, IsIndex: true})
+}
+
+func (p Path) append(segment Segment) Path {
+	child := make(Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, segment)
+}
+
+func (p Path) Parent() Path {
+	if len(p) == 0 {
+		return p
+	}
+	return p[:len(p)-1]
+}
+
+func (p Path) Last() Segment {
+	if len(p) == 0 {
+		return Segment{}
+	}
+	return p[len(p)-1]
+}
+
+func (p Path) HasPrefix(prefix Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i := range prefix {
+		if p[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Path) Equal(other Path) bool {
+	return len(p) == len(other) && p.HasPrefix(other)
+}
+
+func (p Path) Compare(other Path) int {
+	for i := 0; i < len(p) && i < len(other); i++ {
+		a, b := p[i], other[i]
+		switch {
+		case a.IsIndex && !b.IsIndex:
+			return -1
+		case !a.IsIndex && b.IsIndex:
+			return 1
+		case a.IsIndex && a.Index != b.Index:
+			if a.Index < b.Index {
+				return -1
+			}
+			return 1
+		case !a.IsIndex && a.Key != b.Key:
+			return strings.Compare(a.Key, b.Key)
+		}
+	}
+	return len(p) - len(other)
+}
+
+func SortPaths(paths []Path) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		return paths[i].Compare(paths[j]) < 0
+	})
+}
+
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, segment := range p {
+		if segment.IsIndex {
+			sb.WriteString("[" + strconv.Itoa(segment.Index) + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(segment.String())
+	}
+	return sb.String()
+}
+
+func (s Segment) String() string {
+	if s.IsIndex {
+		return "[" + strconv.Itoa(s.Index) + "]"
+	}
+	if s.Key == "" || strings.ContainsAny(s.Key, ".[]\"\\ \t\n") {
+		return strconv.Quote(s.Key)
+	}
+	return s.Key
+}
@@ -0,0 +1,127 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Path
+		wantErr  bool
+	}{
+		{input: "", expected: nil},
+		{input: "a", expected: KeyPath("a")},
+		{input: "a.b.c", expected: KeyPath("a", "b", "c")},
+		{input: `podAnnotations."prometheus.io/scrape"`, expected: KeyPath("podAnnotations", "prometheus.io/scrape")},
+		{input: `a."say \"hi\"".b`, expected: KeyPath("a", `say "hi"`, "b")},
+		{input: `a.""`, expected: KeyPath("a", "")},
+		{input: "extraEnv[0].name", expected: KeyPath("extraEnv").Item(0).Child("name")},
+		{input: "matrix[1][2]", expected: KeyPath("matrix").Item(1).Item(2)},
+		{input: "a..b", wantErr: true},
+		{input: "a.", wantErr: true},
+		{input: ".a", wantErr: true},
+		{input: "a[x]", wantErr: true},
+		{input: "a[-1]", wantErr: true},
+		{input: "a[1", wantErr: true},
+		{input: `a."b`, wantErr: true},
+		{input: `a."b"c`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePath(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePath(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParsePath(%q) = %#v, want %#v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPathString(t *testing.T) {
+	tests := []struct {
+		path     Path
+		expected string
+	}{
+		{KeyPath("a", "b"), "a.b"},
+		{KeyPath("podAnnotations", "prometheus.io/scrape"), `podAnnotations."prometheus.io/scrape"`},
+		{KeyPath("a", ""), `a.""`},
+		{KeyPath("a", "with space"), `a."with space"`},
+		{KeyPath("a", "x[0]"), `a."x[0]"`},
+		{KeyPath("extraEnv").Item(0).Child("name"), "extraEnv[0].name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			got := tt.path.String()
+			if got != tt.expected {
+				t.Errorf("String() = %q, want %q", got, tt.expected)
+			}
+
+			parsed, err := ParsePath(got)
+			if err != nil {
+				t.Fatalf("ParsePath(%q) error = %v", got, err)
+			}
+			if !parsed.Equal(tt.path) {
+				t.Errorf("ParsePath(%q) = %#v, want %#v", got, parsed, tt.path)
+			}
+		})
+	}
+}
+
+func TestPathHasPrefix(t *testing.T) {
+	tests := []struct {
+		path     string
+		prefix   string
+		expected bool
+	}{
+		{"a.b", "a", true},
+		{"a.b", "a.b", true},
+		{"a", "a.b", false},
+		{"ab", "a", false},
+		{`a."b.c"`, "a.b", false},
+		{"a[0].b", "a[0]", true},
+		{"a[1].b", "a[0]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+"/"+tt.prefix, func(t *testing.T) {
+			path, err := ParsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prefix, err := ParsePath(tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := path.HasPrefix(prefix); got != tt.expected {
+				t.Errorf("HasPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSortPaths(t *testing.T) {
+	paths := []Path{
+		KeyPath("b"),
+		KeyPath("a", "b-c"),
+		KeyPath("a", "b", "c"),
+		KeyPath("a").Item(10),
+		KeyPath("a", "b"),
+		KeyPath("a").Item(2),
+		KeyPath("a"),
+	}
+
+	SortPaths(paths)
+
+	expected := []string{"a", "a[2]", "a[10]", "a.b", "a.b.c", "a.b-c", "b"}
+	for i, path := range paths {
+		if path.String() != expected[i] {
+			t.Errorf("SortPaths()[%d] = %s, want %s", i, path, expected[i])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package values
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const PinMarker = "valgrade:pin"
+
+func Pinned(node *yaml.Node) []Path {
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return nil
+	}
+
+	return pinnedPaths(nil, node.Content[0])
+}
+
+func pinnedPaths(prefix Path, node *yaml.Node) []Path {
+	var paths []Path
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := prefix.Child(key.Value)
+			if hasPinMarker(key.HeadComment, key.LineComment, value.LineComment) {
+				paths = append(paths, path)
+				continue
+			}
+			paths = append(paths, pinnedPaths(path, value)...)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			path := prefix.Item(i)
+			if hasPinMarker(item.HeadComment, item.LineComment) {
+				paths = append(paths, path)
+				continue
+			}
+			paths = append(paths, pinnedPaths(path, item)...)
+		}
+	}
+
+	return paths
+}
+
+func hasPinMarker(comments ...string) bool {
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			if strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#")) == PinMarker {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPinned(t *testing.T) {
+	yamlContent := `
+replicas: 1 # valgrade:pin
+image:
+  # valgrade:pin
+  tag: "1.0"
+  repository: nginx # not a pin
+# valgrade:pin
+resources:
+  limits:
+    cpu: 100m
+extraEnv:
+  - name: A
+  - name: B # valgrade:pin
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &node); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Path{
+		KeyPath("replicas"),
+		KeyPath("image", "tag"),
+		KeyPath("resources"),
+		KeyPath("extraEnv").Item(1).Child("name"),
+	}
+
+	if got := Pinned(&node); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Pinned() = %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,280 @@
+package values
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+type Tree struct {
+	Root    *yaml.Node
+	sources map[*yaml.Node]string
+}
+
+func NewTree(node *yaml.Node, file string) *Tree {
+	t := &Tree{sources: make(map[*yaml.Node]string)}
+
+	if node != nil && node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			node = nil
+		} else {
+			node = node.Content[0]
+		}
+	}
+	if node == nil || isNull(node) {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	t.Root = t.normalize(node, file)
+	return t
+}
+
+func TreeFromMap(m map[string]interface{}, file string) (*Tree, error) {
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+
+	var node yaml.Node
+	if err := node.Encode(m); err != nil {
+		return nil, fmt.Errorf("failed to encode values: %w", err)
+	}
+
+	return NewTree(&node, file), nil
+}
+
+func FileTree(files []*File) *Tree {
+	trees := make([]*Tree, 0, len(files))
+	for _, f := range files {
+		trees = append(trees, NewTree(f.Node, f.Path))
+	}
+
+	return MergeTrees(trees...)
+}
+
+func MergeTrees(trees ...*Tree) *Tree {
+	merged := NewTree(nil, "")
+	for _, t := range trees {
+		for node, file := range t.sources {
+			merged.sources[node] = file
+		}
+		merged.Root = merged.merge(merged.Root, t.Root)
+	}
+
+	return merged
+}
+
+func NestTree(key string, t *Tree) *Tree {
+	nested := NewTree(nil, "")
+	for node, file := range t.sources {
+		nested.sources[node] = file
+	}
+	nested.Root.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, t.Root}
+
+	return nested
+}
+
+func (t *Tree) Lookup(path Path) (*yaml.Node, bool) {
+	current := t.Root
+	for _, segment := range path {
+		value, _ := child(current, segment)
+		if value == nil {
+			return nil, false
+		}
+		current = value
+	}
+
+	return current, true
+}
+
+func (t *Tree) Position(node *yaml.Node) Position {
+	if node == nil {
+		return Position{}
+	}
+	return Position{File: t.sources[node], Line: node.Line, Column: node.Column}
+}
+
+func (t *Tree) Locate(path Path) (Position, bool) {
+	var found Position
+	ok := false
+
+	current := t.Root
+	for _, segment := range path {
+		value, i := child(current, segment)
+		if value == nil {
+			break
+		}
+		anchor := value
+		if !segment.IsIndex {
+			anchor = current.Content[i]
+		}
+		if file := t.sources[anchor]; anchor.Line > 0 || found.Line == 0 || file != found.File {
+			found, ok = Position{File: file, Line: anchor.Line, Column: anchor.Column}, true
+		}
+		current = value
+	}
+
+	return found, ok
+}
+
+func (t *Tree) Contains(node *yaml.Node) bool {
+	_, ok := t.sources[node]
+	return ok
+}
+
+func (t *Tree) LeafPaths() []Path {
+	paths := leafPaths(nil, t.Root)
+	SortPaths(paths)
+	return paths
+}
+
+func leafPaths(prefix Path, node *yaml.Node) []Path {
+	var paths []Path
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		path := prefix.Child(node.Content[i].Value)
+		if value := node.Content[i+1]; value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			paths = append(paths, leafPaths(path, value)...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (t *Tree) Decode() (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if err := t.Root.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode values: %w", err)
+	}
+
+	return m, nil
+}
+
+func (t *Tree) normalize(node *yaml.Node, file string) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return t.normalize(node.Alias, file)
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		var merged, explicit []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Tag != "!!merge" {
+				t.sources[key] = file
+				explicit = setEntry(explicit, key, t.normalize(value, file))
+				continue
+			}
+			for _, source := range mergeSources(value) {
+				source = t.normalize(source, file)
+				for j := 0; j+1 < len(source.Content); j += 2 {
+					merged = setEntry(merged, source.Content[j], source.Content[j+1])
+				}
+			}
+		}
+
+		out := *node
+		out.Content = merged
+		for i := 0; i+1 < len(explicit); i += 2 {
+			out.Content = setEntry(out.Content, explicit[i], explicit[i+1])
+		}
+		t.sources[&out] = file
+		return &out
+	case yaml.SequenceNode:
+		out := *node
+		out.Content = make([]*yaml.Node, len(node.Content))
+		for i, item := range node.Content {
+			out.Content[i] = t.normalize(item, file)
+		}
+		t.sources[&out] = file
+		return &out
+	}
+
+	t.sources[node] = file
+	return node
+}
+
+func (t *Tree) merge(dst, src *yaml.Node) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+
+	out := *src
+	out.Content = append([]*yaml.Node(nil), dst.Content...)
+	out.Content = t.mergeContent(out.Content, src)
+	t.sources[&out] = t.sources[src]
+	return &out
+}
+
+func (t *Tree) mergeContent(content []*yaml.Node, src *yaml.Node) []*yaml.Node {
+	if src.Kind != yaml.MappingNode {
+		return content
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		for j := 0; j+1 < len(content); j += 2 {
+			if content[j].Value == key.Value {
+				if merged := t.merge(content[j+1], value); merged != value {
+					key, value = content[j], merged
+				}
+				break
+			}
+		}
+		content = setEntry(content, key, value)
+	}
+
+	return content
+}
+
+func CopyNode(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return CopyNode(node.Alias)
+	}
+
+	out := &yaml.Node{Kind: node.Kind, Style: node.Style, Tag: node.Tag, Value: node.Value}
+	for _, item := range node.Content {
+		out.Content = append(out.Content, CopyNode(item))
+	}
+	return out
+}
+
+func mergeSources(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.SequenceNode {
+		sources := make([]*yaml.Node, 0, len(node.Content))
+		for i := len(node.Content) - 1; i >= 0; i-- {
+			sources = append(sources, node.Content[i])
+		}
+		return sources
+	}
+	return []*yaml.Node{node}
+}
+
+func setEntry(content []*yaml.Node, key, value *yaml.Node) []*yaml.Node {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key.Value {
+			content[i], content[i+1] = key, value
+			return content
+		}
+	}
+	return append(content, key, value)
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
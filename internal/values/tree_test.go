@@ -0,0 +1,179 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFileTree(t *testing.T) {
+	base, err := Parse("values.yaml", []byte(`defaults: &defaults
+  pullPolicy: IfNotPresent
+  tag: "1.0"
+image:
+  <<: *defaults
+  tag: "1.1"
+replicas: 1
+`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, err := Parse("prod.yaml", []byte(`image:
+  tag: "2.0"
+resources: ~
+`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := TreeFromMap(map[string]interface{}{"replicas": 3}, "--set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := MergeTrees(FileTree([]*File{base, prod}), overrides)
+
+	tests := []struct {
+		path     Path
+		value    string
+		position string
+	}{
+		{path: KeyPath("image", "pullPolicy"), value: "IfNotPresent", position: "values.yaml:2:15"},
+		{path: KeyPath("image", "tag"), value: "2.0", position: "prod.yaml:2:8"},
+		{path: KeyPath("defaults", "tag"), value: "1.0", position: "values.yaml:3:8"},
+		{path: KeyPath("resources"), value: "~", position: "prod.yaml:3:12"},
+		{path: KeyPath("replicas"), value: "3", position: "--set"},
+	}
+	for _, tt := range tests {
+		node, ok := tree.Lookup(tt.path)
+		if !ok {
+			t.Errorf("Lookup(%s) not found", tt.path)
+			continue
+		}
+		if node.Value != tt.value {
+			t.Errorf("Lookup(%s) = %q, want %q", tt.path, node.Value, tt.value)
+		}
+		if got := tree.Position(node).String(); got != tt.position {
+			t.Errorf("Position(%s) = %q, want %q", tt.path, got, tt.position)
+		}
+	}
+
+	if _, ok := tree.Lookup(KeyPath("image", "<<")); ok {
+		t.Errorf("Lookup(image.<<) found the merge key")
+	}
+
+	decoded, err := tree.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	merged, err := Merge([]*File{base, prod})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged = MergeMaps(merged, map[string]interface{}{"replicas": 3})
+	if !reflect.DeepEqual(decoded, merged) {
+		t.Errorf("Decode() = %v, want %v", decoded, merged)
+	}
+}
+
+func TestNewTreeEmpty(t *testing.T) {
+	f, err := Parse("values.yaml", []byte("# no values\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := NewTree(f.Node, f.Path).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(decoded) != 0 {
+		t.Errorf("Decode() = %v, want empty values", decoded)
+	}
+}
+
+func TestTreeLeafPaths(t *testing.T) {
+	tree, err := TreeFromMap(map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{
+			"c": "value",
+			"d": map[string]interface{}{"e": true},
+		},
+		"f": map[string]interface{}{},
+		"g": []interface{}{1, 2},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Path{KeyPath("a"), KeyPath("b", "c"), KeyPath("b", "d", "e"), KeyPath("f"), KeyPath("g")}
+	if got := tree.LeafPaths(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("LeafPaths() = %v, want %v", got, expected)
+	}
+}
+
+func TestCopyNode(t *testing.T) {
+	f, err := Parse("values.yaml", []byte("defaults: &defaults\n  tag: \"2.0\" # pinned\nimage: *defaults\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, ok := NewTree(f.Node, f.Path).Lookup(KeyPath("image"))
+	if !ok {
+		t.Fatal("Lookup(image) not found")
+	}
+	copied := CopyNode(node)
+	if copied == node || copied.Content[1] == node.Content[1] {
+		t.Error("CopyNode() shares nodes with the original")
+	}
+
+	out, err := yaml.Marshal(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "tag: \"2.0\"\n" {
+		t.Errorf("CopyNode() = %q, want the tag with its style and without comments", out)
+	}
+}
+
+func TestTreeLocate(t *testing.T) {
+	base, err := Parse("values.yaml", []byte("replicas: 1\nimage:\n  repository: nginx\nports:\n  - 80\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, err := Parse("prod.yaml", []byte("replicas: 3\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetNode(base.Node, KeyPath("image", "tag"), &yaml.Node{Kind: yaml.ScalarNode, Value: "2.0"}); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := TreeFromMap(map[string]interface{}{"image": map[string]interface{}{"pullPolicy": "Always"}}, "--set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := MergeTrees(FileTree([]*File{base, prod}), overrides)
+
+	tests := []struct {
+		name     string
+		path     Path
+		position string
+		found    bool
+	}{
+		{name: "key of the last file", path: KeyPath("replicas"), position: "prod.yaml:1:1", found: true},
+		{name: "key of a nested map", path: KeyPath("image", "repository"), position: "values.yaml:3:3", found: true},
+		{name: "list item", path: KeyPath("ports").Item(0), position: "values.yaml:5:5", found: true},
+		{name: "added key falls back to its parent", path: KeyPath("image", "tag"), position: "values.yaml:2:1", found: true},
+		{name: "missing key falls back to its parent", path: KeyPath("image", "digest"), position: "values.yaml:2:1", found: true},
+		{name: "command line override", path: KeyPath("image", "pullPolicy"), position: "--set", found: true},
+		{name: "missing top-level key", path: KeyPath("service"), found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, found := tree.Locate(tt.path)
+			if found != tt.found || position.String() != tt.position {
+				t.Errorf("Locate(%s) = %q, %v, want %q, %v", tt.path, position, found, tt.position, tt.found)
+			}
+		})
+	}
+}
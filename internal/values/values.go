@@ -2,6 +2,7 @@ package values
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"gopkg.in/yaml.v3"
@@ -19,6 +20,10 @@ func Load(filename string) (*yaml.Node, error) {
 		return nil, fmt.Errorf("failed to unmarshal values: %w", err)
 	}
 
+	if node.Kind == 0 {
+		node = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
 	return &node, nil
 }
 
@@ -29,42 +34,97 @@ func Write(filename string, node *yaml.Node) error {
 	}
 	defer f.Close()
 
-	encoder := yaml.NewEncoder(f)
+	return Encode(f, node)
+}
+
+func Encode(w io.Writer, node *yaml.Node) error {
+	encoder := yaml.NewEncoder(w)
 	encoder.SetIndent(2)
-	err = encoder.Encode(node)
+	err := encoder.Encode(node)
 	if err != nil {
 		return fmt.Errorf("failed to encode values: %w", err)
 	}
 
-	return nil
+	return encoder.Close()
 }
 
 func GetValue(node *yaml.Node, keys ...string) (string, error) {
+	value, err := Lookup(node, KeyPath(keys...))
+	if err != nil {
+		return "", err
+	}
+
+	return value.Value, nil
+}
+
+func SetValue(node *yaml.Node, newValue string, keys ...string) error {
+	return SetPath(node, KeyPath(keys...), newValue)
+}
+
+func DeleteValue(node *yaml.Node, keys ...string) error {
+	return DeletePath(node, KeyPath(keys...))
+}
+
+func Lookup(node *yaml.Node, path Path) (*yaml.Node, error) {
 	if node.Kind != yaml.DocumentNode {
-		return "", fmt.Errorf("expected document node")
+		return nil, fmt.Errorf("expected document node")
 	}
 
 	current := node.Content[0]
-	for _, key := range keys {
-		found := false
-		for i := 0; i < len(current.Content); i += 2 {
-			if current.Content[i].Value == key {
-				current = current.Content[i+1]
-				found = true
-				break
-			}
+	for _, segment := range path {
+		value, _ := child(current, segment)
+		if value == nil {
+			return nil, fmt.Errorf("key not found: %s", segment)
 		}
-		if !found {
-			return "", fmt.Errorf("key not found: %s", key)
+		current = value
+	}
+
+	return current, nil
+}
+
+func SetPath(node *yaml.Node, path Path, newValue string) error {
+	parent, err := parentNode(node, path)
+	if err != nil {
+		return err
+	}
+
+	last := path[len(path)-1]
+	if value, _ := child(parent, last); value != nil {
+		value.Value = newValue
+		return nil
+	}
+
+	return appendChild(parent, last, &yaml.Node{Kind: yaml.ScalarNode, Value: newValue})
+}
+
+func SetNode(node *yaml.Node, path Path, newValue *yaml.Node) error {
+	parent, err := parentNode(node, path)
+	if err != nil {
+		return err
+	}
+
+	last := path[len(path)-1]
+	if value, i := child(parent, last); value != nil {
+		head, line, foot := value.HeadComment, value.LineComment, value.FootComment
+		*value = *newValue
+		value.HeadComment, value.FootComment = head, foot
+		if value.Kind == yaml.ScalarNode || last.IsIndex {
+			value.LineComment = line
+		} else if parent.Content[i].LineComment == "" {
+			parent.Content[i].LineComment = line
 		}
+		return nil
 	}
 
-	return current.Value, nil
+	return appendChild(parent, last, newValue)
 }
 
-func SetValue(node *yaml.Node, newValue string, keys ...string) error {
+func parentNode(node *yaml.Node, path Path) (*yaml.Node, error) {
 	if node.Kind != yaml.DocumentNode {
-		return fmt.Errorf("expected document node")
+		return nil, fmt.Errorf("expected document node")
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
 	}
 
 	if len(node.Content) == 0 {
@@ -72,63 +132,93 @@ func SetValue(node *yaml.Node, newValue string, keys ...string) error {
 	}
 
 	current := node.Content[0]
-	for _, key := range keys[:len(keys)-1] {
-		found := false
-		for i := 0; i < len(current.Content); i += 2 {
-			if current.Content[i].Value == key {
-				current = current.Content[i+1]
-				found = true
-				break
+	for i, segment := range path[:len(path)-1] {
+		value, _ := child(current, segment)
+		if value == nil {
+			value = &yaml.Node{Kind: yaml.MappingNode}
+			if path[i+1].IsIndex {
+				value = &yaml.Node{Kind: yaml.SequenceNode}
+			}
+			if err := appendChild(current, segment, value); err != nil {
+				return nil, err
 			}
 		}
-		if !found {
-			newMap := &yaml.Node{Kind: yaml.MappingNode}
-			current.Content = append(current.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, newMap)
-			current = newMap
-		}
+		current = value
 	}
 
-	lastKey := keys[len(keys)-1]
-	for i := 0; i < len(current.Content); i += 2 {
-		if current.Content[i].Value == lastKey {
-			current.Content[i+1].Value = newValue
-			return nil
+	return current, nil
+}
+
+func DeletePath(node *yaml.Node, path Path) error {
+	if node.Kind != yaml.DocumentNode {
+		return fmt.Errorf("expected document node")
+	}
+	if len(path) == 0 {
+		return fmt.Errorf("empty path")
+	}
+
+	current := node.Content[0]
+	for _, segment := range path[:len(path)-1] {
+		value, _ := child(current, segment)
+		if value == nil {
+			return fmt.Errorf("key not found: %s", segment)
 		}
+		current = value
+	}
+
+	last := path[len(path)-1]
+	value, i := child(current, last)
+	if value == nil {
+		return fmt.Errorf("key not found: %s", last)
 	}
 
-	current.Content = append(current.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: lastKey}, &yaml.Node{Kind: yaml.ScalarNode, Value: newValue})
+	if last.IsIndex {
+		current.Content = append(current.Content[:i], current.Content[i+1:]...)
+		return nil
+	}
+
+	current.Content = append(current.Content[:i], current.Content[i+2:]...)
 	return nil
 }
 
-func DeleteValue(node *yaml.Node, keys ...string) error {
-	if node.Kind != yaml.DocumentNode {
-		return fmt.Errorf("expected document node")
+func child(node *yaml.Node, segment Segment) (*yaml.Node, int) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
 	}
 
-	current := node.Content[0]
-	for _, key := range keys[:len(keys)-1] {
-		found := false
-		for i := 0; i < len(current.Content); i += 2 {
-			if current.Content[i].Value == key {
-				current = current.Content[i+1]
-				found = true
-				break
-			}
+	if segment.IsIndex {
+		if node.Kind != yaml.SequenceNode || segment.Index >= len(node.Content) {
+			return nil, -1
 		}
-		if !found {
-			return fmt.Errorf("key not found: %s", key)
+		return node.Content[segment.Index], segment.Index
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, -1
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == segment.Key {
+			return node.Content[i+1], i
 		}
 	}
 
-	lastKey := keys[len(keys)-1]
-	for i := 0; i < len(current.Content); i += 2 {
-		if current.Content[i].Value == lastKey {
-			current.Content = append(current.Content[:i], current.Content[i+2:]...)
-			return nil
+	return nil, -1
+}
+
+func appendChild(node *yaml.Node, segment Segment, value *yaml.Node) error {
+	if segment.IsIndex {
+		if node.Kind != yaml.SequenceNode || segment.Index != len(node.Content) {
+			return fmt.Errorf("index out of range: %s", segment)
 		}
+		node.Content = append(node.Content, value)
+		return nil
 	}
 
-	return fmt.Errorf("key not found: %s", lastKey)
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("value at key %s is not a map", segment)
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: segment.Key}, value)
+	return nil
 }
 
 func SetNestedValue(m map[string]interface{}, value interface{}, keys ...string) error {
@@ -155,3 +245,24 @@ func SetNestedValue(m map[string]interface{}, value interface{}, keys ...string)
 
 	return SetNestedValue(subMap, value, restKeys...)
 }
+
+func Prune(node *yaml.Node, path Path) error {
+	if err := DeletePath(node, path); err != nil {
+		return err
+	}
+
+	for parent := path.Parent(); len(parent) > 0; parent = parent.Parent() {
+		value, err := Lookup(node, parent)
+		if err != nil {
+			return err
+		}
+		if value.Kind != yaml.MappingNode || len(value.Content) > 0 {
+			return nil
+		}
+		if err := DeletePath(node, parent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
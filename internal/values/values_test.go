@@ -1,6 +1,7 @@
 package values
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -354,3 +355,134 @@ func TestSetNestedValue(t *testing.T) {
 		})
 	}
 }
+
+func TestPathOperations(t *testing.T) {
+	yamlContent := `
+podAnnotations:
+  prometheus.io/scrape: "false"
+extraEnv:
+  - name: A
+    value: "1"
+  - name: B
+    value: "2"
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &node); err != nil {
+		t.Fatal(err)
+	}
+
+	scrape := KeyPath("podAnnotations", "prometheus.io/scrape")
+	if err := SetPath(&node, scrape, "true"); err != nil {
+		t.Fatalf("SetPath() error = %v", err)
+	}
+	if got, err := Lookup(&node, scrape); err != nil || got.Value != "true" {
+		t.Errorf("Lookup(%s) = %v, %v, want true", scrape, got, err)
+	}
+
+	secondValue := KeyPath("extraEnv").Item(1).Child("value")
+	if err := SetPath(&node, secondValue, "3"); err != nil {
+		t.Fatalf("SetPath() error = %v", err)
+	}
+	if got, err := Lookup(&node, secondValue); err != nil || got.Value != "3" {
+		t.Errorf("Lookup(%s) = %v, %v, want 3", secondValue, got, err)
+	}
+
+	if err := SetPath(&node, KeyPath("extraEnv").Item(2).Child("name"), "C"); err != nil {
+		t.Fatalf("SetPath() appending to a list error = %v", err)
+	}
+	if err := SetPath(&node, KeyPath("extraEnv").Item(5).Child("name"), "D"); err == nil {
+		t.Errorf("SetPath() beyond the end of a list expected error")
+	}
+
+	if err := DeletePath(&node, KeyPath("extraEnv").Item(0)); err != nil {
+		t.Fatalf("DeletePath() error = %v", err)
+	}
+	if got, err := Lookup(&node, KeyPath("extraEnv").Item(0).Child("name")); err != nil || got.Value != "B" {
+		t.Errorf("Lookup(extraEnv[0].name) = %v, %v, want B", got, err)
+	}
+
+	if err := DeletePath(&node, scrape); err != nil {
+		t.Fatalf("DeletePath() error = %v", err)
+	}
+	if _, err := Lookup(&node, scrape); err == nil {
+		t.Errorf("Value still exists after deletion")
+	}
+}
+
+func TestSetNode(t *testing.T) {
+	yamlContent := `
+route:
+  # the default receiver
+  receiver: default # inline
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &node); err != nil {
+		t.Fatal(err)
+	}
+
+	var receivers yaml.Node
+	if err := receivers.Encode([]interface{}{"default", "pager"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetNode(&node, KeyPath("route", "receiver"), &receivers); err != nil {
+		t.Fatalf("SetNode() error = %v", err)
+	}
+	if err := SetNode(&node, KeyPath("route", "group_wait"), &yaml.Node{Kind: yaml.ScalarNode, Value: "30s"}); err != nil {
+		t.Fatalf("SetNode() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, &node); err != nil {
+		t.Fatal(err)
+	}
+	expected := `route:
+  # the default receiver
+  receiver: # inline
+    - default
+    - pager
+  group_wait: 30s
+`
+	if buf.String() != expected {
+		t.Errorf("SetNode() result:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	yamlContent := `# values
+replicas: 1
+image:
+  tag: "1.0"
+resources:
+  limits:
+    cpu: 100m
+  requests: {}
+service:
+  ports: {}
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &node); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []Path{KeyPath("image", "tag"), KeyPath("resources", "limits", "cpu")} {
+		if err := Prune(&node, path); err != nil {
+			t.Fatalf("Prune(%s) error = %v", path, err)
+		}
+	}
+
+	got, err := yaml.Marshal(&node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# values
+replicas: 1
+resources:
+    requests: {}
+service:
+    ports: {}
+`
+	if string(got) != expected {
+		t.Errorf("Prune() = %s, want %s", got, expected)
+	}
+}